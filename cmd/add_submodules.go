@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -12,42 +11,106 @@ import (
 var addSubmodulesCmd = &cobra.Command{
 	Use:   "add-submodules",
 	Short: "Add repositories as submodules",
+	Long: `Add repositories as submodules.
+
+Repositories already registered in .gitmodules are skipped. An existing clone
+of the same repository is converted into a submodule in place, keeping its
+objects and local commits. Any other existing directory is only replaced if it
+has no uncommitted changes and no unpushed commits, unless --force is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		addSubmodules()
+		branch, _ := cmd.Flags().GetString("branch")
+		force, _ := cmd.Flags().GetBool("force")
+		addSubmodules(branch, force)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(addSubmodulesCmd)
+	addSubmodulesCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "Branch to track for repositories that do not list one")
+	addSubmodulesCmd.Flags().Bool("force", false, "Replace directories even if they have uncommitted or unpushed work")
 }
 
-func addSubmodules() {
-	repos, err := readLines("github_repositories.txt")
+func addSubmodules(defaultBranch string, force bool) {
+	repos, err := readRepos("github_repositories.txt")
 	if err != nil {
 		fmt.Println("Error reading repositories file:", err)
 		return
 	}
 
-	for _, repo := range repos {
-		repoDir := filepath.Base(repo)
-		url := fmt.Sprintf("https://github.com/%s.git", repo)
+	existing, err := readGitmodules(".")
+	if err != nil {
+		fmt.Println("Error reading .gitmodules:", err)
+		return
+	}
 
-		// Remove existing directory
-		fmt.Printf("Removing existing directory: %s\n", repoDir)
-		if err := os.RemoveAll(repoDir); err != nil {
-			fmt.Printf("Error removing directory %s: %v\n", repoDir, err)
+	for _, repo := range repos {
+		if sm, ok := existing[repo.Dir()]; ok {
+			fmt.Printf("Submodule %s is already registered (branch %s), skipping.\n", sm.Path, sm.Branch)
 			continue
 		}
-
-		// Add submodule
-		fmt.Printf("Adding submodule for %s\n", repo)
-		cmd := exec.Command("git", "submodule", "add", "-b", "protobuf-4.x-rc", url, repoDir)
-		if output, err := cmd.CombinedOutput(); err != nil {
-			fmt.Printf("Error adding submodule %s: %s\n%s", repo, err, output)
+		if err := addSubmodule(".", repo, repo.BranchOr(defaultBranch), force); err != nil {
+			fmt.Printf("Error adding submodule %s: %v\n", repo.FullName(), err)
 			continue
 		}
-		fmt.Printf("Successfully added submodule for %s\n", repo)
+		fmt.Printf("Successfully added submodule for %s\n", repo.FullName())
 	}
 
 	fmt.Println("All submodules added.")
 }
+
+// addSubmodule registers repo as a submodule of the repository in parentDir.
+func addSubmodule(parentDir string, repo repoEntry, branch string, force bool) error {
+	repoDir := repo.Dir()
+	path := filepath.Join(parentDir, repoDir)
+
+	if _, err := os.Stat(path); err == nil {
+		if isGitRepo(path) {
+			if origin, err := remoteURL(path, "origin"); err == nil && sameRepoURL(origin, repo.URL()) {
+				fmt.Printf("Converting existing clone %s into a submodule\n", repoDir)
+				if _, err := runGit(parentDir, "submodule", "add", "-b", branch, repo.URL(), repoDir); err != nil {
+					return err
+				}
+				_, err := runGit(parentDir, "submodule", "absorbgitdirs", repoDir)
+				return err
+			}
+		}
+
+		if !force {
+			if err := checkDiscardable(path, branch); err != nil {
+				return fmt.Errorf("refusing to replace %s: %w (use --force to override)", repoDir, err)
+			}
+		}
+		fmt.Printf("Removing existing directory: %s\n", repoDir)
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Adding submodule for %s\n", repo.FullName())
+	_, err := runGit(parentDir, "submodule", "add", "-b", branch, repo.URL(), repoDir)
+	return err
+}
+
+// checkDiscardable returns an error unless the directory at path is a git
+// repository with a clean working tree and no commits missing from
+// origin/<branch>.
+func checkDiscardable(path, branch string) error {
+	if !isGitRepo(path) {
+		return fmt.Errorf("it is not a git repository")
+	}
+	dirty, err := isDirty(path)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("it has uncommitted changes")
+	}
+	ahead, err := commitsAhead(path, "origin/"+branch)
+	if err != nil {
+		return fmt.Errorf("could not compare with origin/%s: %w", branch, err)
+	}
+	if ahead > 0 {
+		return fmt.Errorf("it has %d commits not pushed to origin/%s", ahead, branch)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runGitT(t *testing.T, dir string, args ...string) string {
+	output, err := runGit(dir, args...)
+	assert.NoError(t, err)
+	return output
+}
+
+func TestAddSubmodule(t *testing.T) {
+	repo := repoEntry{Owner: "googleapis", Name: "java-storage"}
+
+	t.Run("converts existing clone in place", func(t *testing.T) {
+		parentDir, err := os.MkdirTemp("", "parent")
+		assert.NoError(t, err)
+		defer os.RemoveAll(parentDir)
+		setupGitRepo(t, parentDir)
+		createCommit(t, parentDir, "initial commit")
+
+		repoDir := filepath.Join(parentDir, "java-storage")
+		assert.NoError(t, os.Mkdir(repoDir, 0755))
+		setupGitRepo(t, repoDir)
+		createCommit(t, repoDir, "unpushed work")
+		runGitT(t, repoDir, "remote", "add", "origin", "https://TOKEN@github.com/googleapis/java-storage.git")
+
+		err = addSubmodule(parentDir, repo, "protobuf-4.x-rc", false)
+		assert.NoError(t, err)
+
+		submodules, err := readGitmodules(parentDir)
+		assert.NoError(t, err)
+		assert.Equal(t, submodule{
+			Name:   "java-storage",
+			Path:   "java-storage",
+			URL:    "https://github.com/googleapis/java-storage.git",
+			Branch: "protobuf-4.x-rc",
+		}, submodules["java-storage"])
+
+		// The local commit survives and the git dir moved into the parent.
+		assert.Equal(t, "unpushed work", runGitT(t, repoDir, "log", "-1", "--format=%s"))
+		_, err = os.Stat(filepath.Join(parentDir, ".git", "modules", "java-storage"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses to replace a directory with work in it", func(t *testing.T) {
+		parentDir, err := os.MkdirTemp("", "parent")
+		assert.NoError(t, err)
+		defer os.RemoveAll(parentDir)
+		setupGitRepo(t, parentDir)
+
+		repoDir := filepath.Join(parentDir, "java-storage")
+		assert.NoError(t, os.Mkdir(repoDir, 0755))
+		err = os.WriteFile(filepath.Join(repoDir, "notes.txt"), []byte("keep me"), 0644)
+		assert.NoError(t, err)
+
+		err = addSubmodule(parentDir, repo, "protobuf-4.x-rc", false)
+		assert.Error(t, err)
+		_, err = os.Stat(filepath.Join(repoDir, "notes.txt"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses to replace a clone with unpushed commits", func(t *testing.T) {
+		originDir, err := os.MkdirTemp("", "origin")
+		assert.NoError(t, err)
+		defer os.RemoveAll(originDir)
+		runGitT(t, originDir, "init", "--bare")
+
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+		setupGitRepo(t, repoDir)
+		createCommit(t, repoDir, "pushed")
+		runGitT(t, repoDir, "remote", "add", "origin", originDir)
+		runGitT(t, repoDir, "push", "origin", "HEAD:refs/heads/protobuf-4.x-rc")
+		runGitT(t, repoDir, "fetch", "origin")
+
+		assert.NoError(t, checkDiscardable(repoDir, "protobuf-4.x-rc"))
+
+		createCommit(t, repoDir, "not pushed")
+		assert.ErrorContains(t, checkDiscardable(repoDir, "protobuf-4.x-rc"), "1 commits not pushed")
+
+		err = os.WriteFile(filepath.Join(repoDir, "dirty.txt"), []byte("dirty"), 0644)
+		assert.NoError(t, err)
+		assert.ErrorContains(t, checkDiscardable(repoDir, "protobuf-4.x-rc"), "uncommitted changes")
+	})
+}
+
+func TestParseRepoEntry(t *testing.T) {
+	entry, err := parseRepoEntry("googleapis/java-storage")
+	assert.NoError(t, err)
+	assert.Equal(t, repoEntry{Owner: "googleapis", Name: "java-storage"}, entry)
+	assert.Equal(t, "protobuf-4.x-rc", entry.BranchOr("protobuf-4.x-rc"))
+
+	entry, err = parseRepoEntry("googleapis/java-storage  main")
+	assert.NoError(t, err)
+	assert.Equal(t, "main", entry.BranchOr("protobuf-4.x-rc"))
+	assert.Equal(t, "https://github.com/googleapis/java-storage.git", entry.URL())
+
+	_, err = parseRepoEntry("java-storage")
+	assert.Error(t, err)
+}
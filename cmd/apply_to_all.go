@@ -11,13 +11,14 @@ import (
 var (
 	sourceOwnerRepo string
 	sourceBranchAll string
+	applyResume     string
 )
 
 var applyToAllCmd = &cobra.Command{
 	Use:   "apply-to-all",
 	Short: "Apply branch protection rules from one repository to all others in github_repositories.txt",
 	Run: func(cmd *cobra.Command, args []string) {
-		repos, err := readLines("github_repositories.txt")
+		repos, err := readRepos("github_repositories.txt")
 		if err != nil {
 			log.Fatalf("Failed to read github_repositories.txt: %v", err)
 		}
@@ -34,25 +35,32 @@ var applyToAllCmd = &cobra.Command{
 			log.Fatalf("Failed to get branch protection from %s/%s: %v", sourceOwner, sourceRepo, err)
 		}
 
-		for _, repoStr := range repos {
-			repoParts := strings.Split(repoStr, "/")
-			if len(repoParts) != 2 {
-				log.Printf("Skipping invalid repo format: %s", repoStr)
-				continue
-			}
-			destOwner := repoParts[0]
-			destRepo := repoParts[1]
+		journal, err := startRun("apply-to-all", applyResume)
+		if err != nil {
+			log.Fatalf("Failed to start run: %v", err)
+		}
+
+		for _, repo := range repos {
+			destOwner := repo.Owner
+			destRepo := repo.Name
+			destBranch := repo.BranchOr(sourceBranchAll)
 
 			if destOwner == sourceOwner && destRepo == sourceRepo {
 				fmt.Printf("Skipping source repository: %s/%s\n", destOwner, destRepo)
 				continue
 			}
 
-			fmt.Printf("Applying branch protection to %s/%s...\n", destOwner, destRepo)
-			err := ApplyBranchProtection(destOwner, destRepo, sourceBranchAll, protection)
+			if journal.Done(repo.FullName(), "apply") {
+				fmt.Printf("Skipping %s/%s: completed in run %s\n", destOwner, destRepo, journal.ID)
+				continue
+			}
+
+			fmt.Printf("Applying branch protection to %s/%s (%s)...\n", destOwner, destRepo, destBranch)
+			err := ApplyBranchProtection(destOwner, destRepo, destBranch, protection)
 			if err != nil {
 				log.Printf("Failed to apply branch protection to %s/%s: %v", destOwner, destRepo, err)
 			} else {
+				journal.Complete(repo.FullName(), "apply")
 				fmt.Printf("Successfully applied branch protection to %s/%s\n", destOwner, destRepo)
 			}
 		}
@@ -63,4 +71,5 @@ func init() {
 	rootCmd.AddCommand(applyToAllCmd)
 	applyToAllCmd.Flags().StringVar(&sourceOwnerRepo, "source-repo", "googleapis/google-auth-library-java", "Source repository in owner/repo format")
 	applyToAllCmd.Flags().StringVar(&sourceBranchAll, "source-branch", "protobuf-4.x-rc", "Branch to get protection rules from")
+	applyToAllCmd.Flags().StringVar(&applyResume, "resume", "", "Resume an interrupted run, skipping repositories already updated")
 }
@@ -10,8 +10,9 @@ import (
 	"golang.org/x/oauth2"
 )
 
-// GetBranchProtection gets the branch protection for a given repository and branch.
-func GetBranchProtection(owner, repo, branch string) (*github.Protection, error) {
+// newGitHubClient creates a GitHub API client authenticated with the token
+// in ~/GITHUB_TOKEN.
+func newGitHubClient() (*github.Client, error) {
 	token, err := readToken("~/GITHUB_TOKEN")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token: %w", err)
@@ -21,8 +22,15 @@ func GetBranchProtection(owner, repo, branch string) (*github.Protection, error)
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(context.Background(), ts)
+	return github.NewClient(tc), nil
+}
 
-	client := github.NewClient(tc)
+// GetBranchProtection gets the branch protection for a given repository and branch.
+func GetBranchProtection(owner, repo, branch string) (*github.Protection, error) {
+	client, err := newGitHubClient()
+	if err != nil {
+		return nil, err
+	}
 	protection, resp, err := client.Repositories.GetBranchProtection(context.Background(), owner, repo, branch)
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
@@ -35,18 +43,11 @@ func GetBranchProtection(owner, repo, branch string) (*github.Protection, error)
 
 // ApplyBranchProtection applies branch protection rules to a given repository and branch.
 func ApplyBranchProtection(owner, repo, branch string, protection *github.Protection) error {
-	token, err := readToken("~/GITHUB_TOKEN")
+	client, err := newGitHubClient()
 	if err != nil {
-		return fmt.Errorf("failed to get token: %w", err)
+		return err
 	}
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(context.Background(), ts)
-
-	client := github.NewClient(tc)
-
 	protectionRequest := &github.ProtectionRequest{}
 	if protection.RequiredStatusChecks != nil {
 		protectionRequest.RequiredStatusChecks = &github.RequiredStatusChecks{
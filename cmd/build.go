@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build and test the repositories with Maven in dependency order",
+	Long: `Build and test the repositories with Maven in dependency order.
+
+The repositories are built one at a time in the order printed by "graph",
+with a shared local Maven repository so that each repository picks up the
+artifacts installed by the repositories it depends on. The output of each
+build is saved to <log-dir>/<repo>.log, the surefire and failsafe reports
+written by the build are counted, and a summary is printed at the end.
+
+A repository is skipped if one of the repositories it depends on failed,
+since it would otherwise build against stale artifacts, unless
+--keep-going is given.`,
+	Example: `  repo-manager build --goals "-DskipTests install"
+  repo-manager build --goals "install" --repos sdk-platform-java,java-storage`,
+	Run: func(cmd *cobra.Command, args []string) {
+		names, _ := cmd.Flags().GetStringSlice("repos")
+		goals, _ := cmd.Flags().GetString("goals")
+		localRepo, _ := cmd.Flags().GetString("local-repo")
+		logDir, _ := cmd.Flags().GetString("log-dir")
+		mvn, _ := cmd.Flags().GetString("mvn")
+		keepGoing, _ := cmd.Flags().GetBool("keep-going")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		graph, err := buildRepoGraph(repos)
+		if err != nil {
+			fmt.Println("Error building dependency graph:", err)
+			os.Exit(1)
+		}
+		order, err := graph.Order()
+		if err != nil {
+			fmt.Println("Error ordering repositories:", err)
+			os.Exit(1)
+		}
+		order, err = selectRepos(order, names)
+		if err != nil {
+			fmt.Println("Error selecting repositories:", err)
+			return
+		}
+
+		opts := buildOptions{Maven: mvn, Goals: strings.Fields(goals), KeepGoing: keepGoing}
+		if opts.LocalRepo, err = filepath.Abs(localRepo); err != nil {
+			fmt.Println("Error resolving local repository:", err)
+			return
+		}
+		if opts.LogDir, err = filepath.Abs(logDir); err != nil {
+			fmt.Println("Error resolving log directory:", err)
+			return
+		}
+		if err := os.MkdirAll(opts.LogDir, 0755); err != nil {
+			fmt.Println("Error creating log directory:", err)
+			return
+		}
+
+		results := buildRepos(order, graph, opts)
+		if printBuildSummary(results) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(buildCmd)
+	buildCmd.Flags().StringSlice("repos", nil, "Only build these repositories (name or owner/name)")
+	buildCmd.Flags().String("goals", "install", "Maven goals and options, e.g. \"-DskipTests install\" or \"verify\"")
+	buildCmd.Flags().String("local-repo", filepath.Join(".repo-manager", "m2"), "The local Maven repository shared by all builds")
+	buildCmd.Flags().String("log-dir", "build-logs", "Directory for the build log of each repository")
+	buildCmd.Flags().String("mvn", "mvn", "The Maven executable")
+	buildCmd.Flags().Bool("keep-going", false, "Build repositories even if a repository they depend on failed")
+}
+
+// buildOptions controls how each repository is built.
+type buildOptions struct {
+	Maven     string
+	Goals     []string
+	LocalRepo string
+	LogDir    string
+	KeepGoing bool
+}
+
+// testCounts are the totals of a set of surefire or failsafe reports.
+type testCounts struct {
+	Tests    int `xml:"tests,attr"`
+	Failures int `xml:"failures,attr"`
+	Errors   int `xml:"errors,attr"`
+	Skipped  int `xml:"skipped,attr"`
+}
+
+func (c *testCounts) add(o testCounts) {
+	c.Tests += o.Tests
+	c.Failures += o.Failures
+	c.Errors += o.Errors
+	c.Skipped += o.Skipped
+}
+
+// buildResult is the outcome of building a single repository.
+type buildResult struct {
+	Repo repoEntry
+	// Status is passed, failed or skipped.
+	Status   string
+	Reason   string
+	Duration time.Duration
+	Tests    testCounts
+	// UnreadableReports counts the test reports left out of Tests because
+	// they could not be read.
+	UnreadableReports int
+	Log               string
+}
+
+// Note returns the reason for the result and mentions unreadable test
+// reports.
+func (r buildResult) Note() string {
+	if r.UnreadableReports == 0 {
+		return r.Reason
+	}
+	note := fmt.Sprintf("unreadable test reports: %d", r.UnreadableReports)
+	if r.Reason != "" {
+		note = r.Reason + "; " + note
+	}
+	return note
+}
+
+// buildRepos builds repos in order, skipping repositories whose
+// dependencies failed unless opts.KeepGoing is set.
+func buildRepos(repos []repoEntry, graph *repoGraph, opts buildOptions) []buildResult {
+	failed := make(map[string]bool)
+	var results []buildResult
+	for _, repo := range repos {
+		var failedDeps []string
+		for _, dep := range graph.Deps[repo.FullName()] {
+			if failed[dep] {
+				failedDeps = append(failedDeps, dep)
+			}
+		}
+		if len(failedDeps) > 0 && !opts.KeepGoing {
+			fmt.Printf("--- Skipping %s: %s failed ---\n", repo.FullName(), strings.Join(failedDeps, ", "))
+			failed[repo.FullName()] = true
+			results = append(results, buildResult{Repo: repo, Status: "skipped", Reason: strings.Join(failedDeps, ", ") + " failed"})
+			continue
+		}
+
+		result := buildRepo(repo, opts)
+		if result.Status == "failed" {
+			failed[repo.FullName()] = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// buildRepo runs Maven in a repository, writing its output to the log and
+// counting the test reports written by the build.
+func buildRepo(repo repoEntry, opts buildOptions) buildResult {
+	fmt.Printf("--- Building %s ---\n", repo.FullName())
+	result := buildResult{Repo: repo, Log: filepath.Join(opts.LogDir, repo.Dir()+".log")}
+	if _, err := os.Stat(repo.Dir()); err != nil {
+		result.Status = "skipped"
+		result.Reason = "not cloned"
+		return result
+	}
+
+	log, err := os.Create(result.Log)
+	if err != nil {
+		result.Status = "failed"
+		result.Reason = err.Error()
+		return result
+	}
+	defer log.Close()
+
+	args := append([]string{"-B", "-ntp", "-Dmaven.repo.local=" + opts.LocalRepo}, opts.Goals...)
+	cmd := exec.Command(opts.Maven, args...)
+	cmd.Dir = repo.Dir()
+	cmd.Stdout = log
+	cmd.Stderr = log
+
+	start := time.Now()
+	err = cmd.Run()
+	result.Duration = time.Since(start).Round(time.Second)
+
+	result.Tests, result.UnreadableReports = readTestReports(repo.Dir(), start)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.Status = "passed"
+	case errors.As(err, &exitErr):
+		result.Status = "failed"
+		result.Reason = fmt.Sprintf("exit %d", exitErr.ExitCode())
+	default:
+		result.Status = "failed"
+		result.Reason = err.Error()
+	}
+	fmt.Printf("%s %s in %s, see %s\n", repo.FullName(), result.Status, result.Duration, result.Log)
+	return result
+}
+
+// readTestReports totals the surefire and failsafe reports under repoDir
+// written since start, ignoring reports left over from earlier builds.
+// Reports and directories that cannot be read are skipped with a warning,
+// and the number of them is returned.
+func readTestReports(repoDir string, since time.Time) (testCounts, int) {
+	// File modification times can be coarser than the clock.
+	since = since.Truncate(time.Second)
+	var total testCounts
+	unreadable := 0
+	skip := func(err error) {
+		fmt.Fprintf(os.Stderr, "Warning: skipping test report %v\n", err)
+		unreadable++
+	}
+	filepath.WalkDir(repoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			skip(err)
+			if d != nil && d.IsDir() && path != repoDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dir := filepath.Base(filepath.Dir(path))
+		if (dir != "surefire-reports" && dir != "failsafe-reports") ||
+			!strings.HasPrefix(d.Name(), "TEST-") || !strings.HasSuffix(d.Name(), ".xml") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			skip(err)
+			return nil
+		}
+		if info.ModTime().Before(since) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			skip(err)
+			return nil
+		}
+		var suite testCounts
+		if err := xml.Unmarshal(data, &suite); err != nil {
+			skip(fmt.Errorf("%s: %w", path, err))
+			return nil
+		}
+		total.add(suite)
+		return nil
+	})
+	return total, unreadable
+}
+
+// printBuildSummary prints a table of the build results and reports
+// whether any repository failed or was skipped because of a failure.
+func printBuildSummary(results []buildResult) bool {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tSTATUS\tTIME\tTESTS\tFAILURES\tERRORS\tSKIPPED\tNOTE")
+	broken := false
+	for _, r := range results {
+		if r.Status != "passed" && r.Reason != "not cloned" {
+			broken = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			r.Repo.Dir(), r.Status, r.Duration, r.Tests.Tests, r.Tests.Failures, r.Tests.Errors, r.Tests.Skipped, r.Note())
+	}
+	w.Flush()
+	return broken
+}
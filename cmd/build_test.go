@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMaven is a stand-in for mvn that records its arguments, writes a
+// surefire report and fails in repositories containing a FAIL file.
+const fakeMaven = `#!/bin/sh
+echo "mvn $@"
+mkdir -p core/target/surefire-reports
+echo '<testsuite name="FooTest" tests="5" failures="1" errors="0" skipped="2"></testsuite>' > core/target/surefire-reports/TEST-FooTest.xml
+test ! -f FAIL
+`
+
+func TestBuildRepos(t *testing.T) {
+	t.Chdir(t.TempDir())
+	repos := []repoEntry{
+		{Owner: "googleapis", Name: "sdk-platform-java"},
+		{Owner: "googleapis", Name: "java-storage"},
+		{Owner: "googleapis", Name: "java-pubsub"},
+	}
+	for _, repo := range repos {
+		assert.NoError(t, os.MkdirAll(repo.Dir(), 0755))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join("sdk-platform-java", "FAIL"), nil, 0644))
+	assert.NoError(t, os.WriteFile("mvn", []byte(fakeMaven), 0755))
+	mvn, err := filepath.Abs("mvn")
+	assert.NoError(t, err)
+	logDir, err := filepath.Abs("logs")
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(logDir, 0755))
+
+	graph := &repoGraph{
+		Repos: repos,
+		Deps:  map[string][]string{"googleapis/java-storage": {"googleapis/sdk-platform-java"}},
+	}
+	opts := buildOptions{Maven: mvn, Goals: []string{"-DskipTests", "install"}, LocalRepo: "/tmp/m2", LogDir: logDir}
+
+	results := buildRepos(repos, graph, opts)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "failed", results[0].Status)
+	assert.Equal(t, testCounts{Tests: 5, Failures: 1, Skipped: 2}, results[0].Tests)
+	assert.Equal(t, "skipped", results[1].Status)
+	assert.Equal(t, "passed", results[2].Status)
+	assert.True(t, printBuildSummary(results))
+
+	log, err := os.ReadFile(filepath.Join(logDir, "java-pubsub.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "mvn -B -ntp -Dmaven.repo.local=/tmp/m2 -DskipTests install", strings.TrimSpace(string(log)))
+
+	opts.KeepGoing = true
+	results = buildRepos(repos, graph, opts)
+	assert.Equal(t, "passed", results[1].Status)
+}
+
+func TestReadTestReports(t *testing.T) {
+	repoDir := t.TempDir()
+	reports := filepath.Join(repoDir, "core", "target", "surefire-reports")
+	assert.NoError(t, os.MkdirAll(reports, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(reports, "TEST-BadTest.xml"), []byte("<testsuite tests="), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(reports, "TEST-FooTest.xml"), []byte(`<testsuite tests="3" failures="1"></testsuite>`), 0644))
+
+	tests, unreadable := readTestReports(repoDir, time.Now().Add(-time.Minute))
+	assert.Equal(t, testCounts{Tests: 3, Failures: 1}, tests)
+	assert.Equal(t, 1, unreadable)
+
+	result := buildResult{Status: "failed", Reason: "exit 1", UnreadableReports: unreadable}
+	assert.Equal(t, "exit 1; unreadable test reports: 1", result.Note())
+}
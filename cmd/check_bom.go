@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var checkBOMCmd = &cobra.Command{
+	Use:   "check-bom",
+	Short: "Compare the versions managed by the BOMs with the versions used by each library",
+	Long: `Compare the versions managed by the BOMs with the versions used by each library.
+
+The BOMs are the POMs with managed dependencies in the --bom-repos
+repositories whose artifactId contains "bom" or ends in "dependencies",
+such as libraries-bom and sdk-platform-java's shared-dependencies. For
+every artifact of the --groups groupIds, the version each BOM manages is
+compared with the versions other BOMs manage and with the versions declared
+in the POMs of every other repository. A BOM imported with
+<scope>import</scope>, such as protobuf-bom or grpc-bom, counts as managing
+its own version and pins every artifact of its group to that version.
+Properties are resolved through parent POMs found in the cloned
+repositories. POMs under samples/ or src/test/ are ignored.
+
+Every mismatch is listed and the command exits with a non-zero status if
+there are any.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		bomRepos, _ := cmd.Flags().GetStringSlice("bom-repos")
+		groups, _ := cmd.Flags().GetStringSlice("groups")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		fleet, err := readFleetPOMs(repos)
+		if err != nil {
+			fmt.Println("Error reading POMs:", err)
+			os.Exit(1)
+		}
+		mismatches := checkBOMs(fleet, bomRepos, groups)
+		printBOMMismatches(mismatches)
+		if len(mismatches) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkBOMCmd)
+	checkBOMCmd.Flags().StringSlice("bom-repos", []string{"java-cloud-bom", "sdk-platform-java"}, "Repositories containing the BOMs")
+	checkBOMCmd.Flags().StringSlice("groups", []string{"com.google.protobuf", "io.grpc"}, "groupIds whose versions are compared")
+}
+
+// fleetPOMs are the POMs of all cloned repositories, by the repositories'
+// owner/name. Like the dependency graph, samples and test POMs are left out.
+type fleetPOMs struct {
+	Repos []repoEntry
+	POMs  map[string][]*pomProject
+	// byKey indexes the POMs by groupId:artifactId to find parents.
+	byKey map[string]*pomProject
+}
+
+func readFleetPOMs(repos []repoEntry) (*fleetPOMs, error) {
+	fleet := &fleetPOMs{Repos: repos, POMs: make(map[string][]*pomProject), byKey: make(map[string]*pomProject)}
+	for _, repo := range repos {
+		if _, err := os.Stat(repo.Dir()); err != nil {
+			continue
+		}
+		poms, err := readRepoPOMs(repo.Dir())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.FullName(), err)
+		}
+		for _, p := range poms {
+			if !isBuildGraphPOM(repo.Dir(), p.Path) {
+				continue
+			}
+			fleet.POMs[repo.FullName()] = append(fleet.POMs[repo.FullName()], p)
+			if _, ok := fleet.byKey[p.Key()]; !ok {
+				fleet.byKey[p.Key()] = p
+			}
+		}
+	}
+	return fleet, nil
+}
+
+// resolve substitutes property references in value using the properties
+// of p and of its parents in the fleet, nearest first.
+func (f *fleetPOMs) resolve(p *pomProject, value string) string {
+	props := pomProperties{}
+	seen := make(map[*pomProject]bool)
+	for cur := p; cur != nil && !seen[cur]; {
+		seen[cur] = true
+		for k, v := range cur.Properties {
+			if _, ok := props[k]; !ok {
+				props[k] = v
+			}
+		}
+		if cur.Parent == nil {
+			break
+		}
+		cur = f.byKey[cur.Parent.Key()]
+	}
+	effective := *p
+	effective.Properties = props
+	return effective.resolve(value)
+}
+
+// isBOM reports whether p looks like a BOM: a POM with managed
+// dependencies named like libraries-bom or shared-dependencies.
+func isBOM(p *pomProject) bool {
+	return len(p.DependencyManagement) > 0 &&
+		(strings.Contains(p.ArtifactID, "bom") || strings.HasSuffix(p.ArtifactID, "dependencies"))
+}
+
+// bomMismatch is an artifact whose version differs from the version a BOM
+// manages.
+type bomMismatch struct {
+	Artifact string
+	BOM      string
+	Managed  string
+	Repo     string
+	Path     string
+	Version  string
+}
+
+// checkBOMs compares the versions managed by the BOMs in bomRepos with
+// each other and with the versions declared by the POMs of the other
+// repositories, for artifacts in groups. Artifacts that no BOM manages
+// directly are compared with the version of the BOM of their group that the
+// BOMs import, such as protobuf-bom for protobuf-java.
+func checkBOMs(fleet *fleetPOMs, bomRepos, groups []string) []bomMismatch {
+	inGroups := func(groupID string) bool {
+		for _, g := range groups {
+			if g == groupID {
+				return true
+			}
+		}
+		return false
+	}
+	isBOMRepo := make(map[string]bool)
+	for _, r := range bomRepos {
+		isBOMRepo[r] = true
+	}
+
+	// managed maps each artifact to the BOMs managing it, in list order.
+	type managedVersion struct {
+		bom     *pomProject
+		repo    string
+		version string
+	}
+	managed := make(map[string][]managedVersion)
+	// imported maps a groupId to the first imported BOM of that group.
+	imported := make(map[string]string)
+	for _, repo := range fleet.Repos {
+		if !isBOMRepo[repo.Name] {
+			continue
+		}
+		for _, p := range fleet.POMs[repo.FullName()] {
+			if !isBOM(p) {
+				continue
+			}
+			for _, d := range p.DependencyManagement {
+				d.GroupID = fleet.resolve(p, d.GroupID)
+				if !inGroups(d.GroupID) || d.Version == "" {
+					continue
+				}
+				version := fleet.resolve(p, d.Version)
+				if strings.Contains(version, "${") {
+					continue
+				}
+				managed[d.Key()] = append(managed[d.Key()], managedVersion{bom: p, repo: repo.Name, version: version})
+				if _, ok := imported[d.GroupID]; !ok && d.Scope == "import" {
+					imported[d.GroupID] = d.Key()
+				}
+			}
+		}
+	}
+
+	var mismatches []bomMismatch
+	for key, versions := range managed {
+		first := versions[0]
+		for _, other := range versions[1:] {
+			if other.version != first.version {
+				mismatches = append(mismatches, bomMismatch{
+					Artifact: key, BOM: first.bom.ArtifactID, Managed: first.version,
+					Repo: other.repo, Path: other.bom.Path, Version: other.version,
+				})
+			}
+		}
+	}
+
+	for _, repo := range fleet.Repos {
+		if isBOMRepo[repo.Name] {
+			continue
+		}
+		for _, p := range fleet.POMs[repo.FullName()] {
+			for _, d := range append(append([]pomDependency{}, p.Dependencies...), p.DependencyManagement...) {
+				d.GroupID = fleet.resolve(p, d.GroupID)
+				versions, ok := managed[d.Key()]
+				if !ok {
+					versions, ok = managed[imported[d.GroupID]]
+				}
+				if !ok || d.Version == "" {
+					continue
+				}
+				// Versions set by properties of parents outside the fleet
+				// cannot be compared.
+				declared := fleet.resolve(p, d.Version)
+				if !strings.Contains(declared, "${") && declared != versions[0].version {
+					mismatches = append(mismatches, bomMismatch{
+						Artifact: d.Key(), BOM: versions[0].bom.ArtifactID, Managed: versions[0].version,
+						Repo: repo.Name, Path: p.Path, Version: declared,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool {
+		a, b := mismatches[i], mismatches[j]
+		if a.Artifact != b.Artifact {
+			return a.Artifact < b.Artifact
+		}
+		return a.Path < b.Path
+	})
+	return mismatches
+}
+
+func printBOMMismatches(mismatches []bomMismatch) {
+	if len(mismatches) == 0 {
+		fmt.Println("All versions match the BOMs")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ARTIFACT\tBOM\tMANAGED\tREPO\tPOM\tVERSION")
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", m.Artifact, m.BOM, m.Managed, m.Repo, m.Path, m.Version)
+	}
+	w.Flush()
+	fmt.Printf("%d versions differ from the BOMs\n", len(mismatches))
+}
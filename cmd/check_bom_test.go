@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckBOMs(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	writePOM(t, "sdk-platform-java/gapic-generator-java-pom-parent/pom.xml", "com.google.api", "gapic-generator-java-pom-parent", "2.45.0", `  <properties>
+    <protobuf.version>4.28.2</protobuf.version>
+    <grpc.version>1.66.0</grpc.version>
+  </properties>`)
+	writePOM(t, "sdk-platform-java/java-shared-dependencies/first-party-dependencies/pom.xml", "com.google.cloud", "first-party-dependencies", "3.35.0", `  <parent>
+    <groupId>com.google.api</groupId>
+    <artifactId>gapic-generator-java-pom-parent</artifactId>
+    <version>2.45.0</version>
+  </parent>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>io.grpc</groupId>
+        <artifactId>grpc-bom</artifactId>
+        <version>${grpc.version}</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+      <dependency>
+        <groupId>com.google.protobuf</groupId>
+        <artifactId>protobuf-bom</artifactId>
+        <version>${protobuf.version}</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>`)
+	writePOM(t, "sdk-platform-java/java-shared-dependencies/pom.xml", "com.google.cloud", "google-cloud-shared-dependencies", "3.35.0", `  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.google.cloud</groupId>
+        <artifactId>first-party-dependencies</artifactId>
+        <version>${project.version}</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>`)
+	writePOM(t, "java-cloud-bom/libraries-bom/pom.xml", "com.google.cloud", "libraries-bom", "26.47.0", `  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.google.protobuf</groupId>
+        <artifactId>protobuf-bom</artifactId>
+        <version>3.25.5</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+      <dependency>
+        <groupId>io.grpc</groupId>
+        <artifactId>grpc-bom</artifactId>
+        <version>1.66.0</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>`)
+	writePOM(t, "java-storage/pom.xml", "com.google.cloud", "google-cloud-storage", "2.43.0", `  <properties>
+    <grpc.version>1.66.0</grpc.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.google.cloud</groupId>
+        <artifactId>google-cloud-shared-dependencies</artifactId>
+        <version>3.35.0</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <dependencies>
+    <dependency>
+      <groupId>com.google.protobuf</groupId>
+      <artifactId>protobuf-java</artifactId>
+      <version>3.25.5</version>
+    </dependency>
+    <dependency>
+      <groupId>io.grpc</groupId>
+      <artifactId>grpc-protobuf</artifactId>
+      <version>${grpc.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>io.grpc</groupId>
+      <artifactId>grpc-api</artifactId>
+      <version>${external.version}</version>
+    </dependency>
+  </dependencies>`)
+	// Samples pin their own versions and are not checked.
+	writePOM(t, "java-storage/samples/pom.xml", "com.example", "storage-samples", "1.0.0", `  <dependencies>
+    <dependency>
+      <groupId>com.google.protobuf</groupId>
+      <artifactId>protobuf-java</artifactId>
+      <version>3.21.0</version>
+    </dependency>
+  </dependencies>`)
+
+	repos := []repoEntry{
+		{Owner: "googleapis", Name: "sdk-platform-java"},
+		{Owner: "googleapis", Name: "java-storage"},
+		{Owner: "googleapis", Name: "java-cloud-bom"},
+	}
+	fleet, err := readFleetPOMs(repos)
+	assert.NoError(t, err)
+
+	mismatches := checkBOMs(fleet, []string{"java-cloud-bom", "sdk-platform-java"}, []string{"com.google.protobuf", "io.grpc"})
+	assert.Equal(t, []bomMismatch{
+		{
+			Artifact: "com.google.protobuf:protobuf-bom", BOM: "first-party-dependencies", Managed: "4.28.2",
+			Repo: "java-cloud-bom", Path: "java-cloud-bom/libraries-bom/pom.xml", Version: "3.25.5",
+		},
+		{
+			Artifact: "com.google.protobuf:protobuf-java", BOM: "first-party-dependencies", Managed: "4.28.2",
+			Repo: "java-storage", Path: "java-storage/pom.xml", Version: "3.25.5",
+		},
+	}, mismatches)
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -14,41 +13,113 @@ var checkBranchCmd = &cobra.Command{
 	Short: "Check the current branch of each repository",
 	Run: func(cmd *cobra.Command, args []string) {
 		branch, _ := cmd.Flags().GetString("branch")
-		checkBranches(branch)
+		fix, _ := cmd.Flags().GetBool("fix")
+		stash, _ := cmd.Flags().GetBool("stash")
+		checkBranches(branch, fix, stash)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(checkBranchCmd)
 	checkBranchCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "Branch to check for")
+	checkBranchCmd.Flags().Bool("fix", false, "Fetch and check out the expected branch in repositories on another branch")
+	checkBranchCmd.Flags().Bool("stash", false, "With --fix, stash uncommitted changes instead of skipping dirty repositories")
 }
 
-func checkBranches(branch string) {
-	repos, err := readLines("github_repositories.txt")
+func checkBranches(branch string, fix, stash bool) {
+	repos, err := readRepos("github_repositories.txt")
 	if err != nil {
 		fmt.Println("Error reading repositories file:", err)
 		return
 	}
 
+	var switched []string
 	for _, repo := range repos {
-		repoDir := filepath.Base(repo)
-		checkBranch(repoDir, branch)
+		repoDir := repo.Dir()
+		expected := repo.BranchOr(branch)
+		if checkBranch(repoDir, expected) || !fix {
+			continue
+		}
+		if err := switchBranch(repoDir, expected, stash); err != nil {
+			fmt.Printf("Error switching '%s' to %s: %v\n", repoDir, expected, err)
+			continue
+		}
+		fmt.Printf("Switched '%s' to %s\n", repoDir, expected)
+		switched = append(switched, repoDir)
+	}
+
+	if fix {
+		if len(switched) == 0 {
+			fmt.Println("No repositories were switched.")
+		} else {
+			fmt.Printf("Switched %d repositories: %s\n", len(switched), strings.Join(switched, ", "))
+		}
 	}
 }
 
-func checkBranch(repoDir, expectedBranch string) {
+// checkBranch reports whether repoDir is on expectedBranch.
+func checkBranch(repoDir, expectedBranch string) bool {
 	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
 	cmd.Dir = repoDir
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		fmt.Printf("Error checking branch for %s: %s\n%s", repoDir, err, output)
-		return
+		return false
 	}
 
 	branch := strings.TrimSpace(string(output))
 	if branch == expectedBranch {
 		fmt.Printf("Repository '%s' is on the correct branch: %s\n", repoDir, branch)
-	} else {
-		fmt.Printf("Repository '%s' is on an incorrect branch: %s\n", repoDir, branch)
+		return true
+	}
+	fmt.Printf("Repository '%s' is on an incorrect branch: %s\n", repoDir, branch)
+	return false
+}
+
+// switchBranch fetches branch from origin and checks it out, creating a
+// local branch tracking origin/<branch> if needed. A dirty working tree is
+// stashed if stash is set and is an error otherwise. If the switch fails
+// the stashed changes are restored, or named in the error if they cannot be.
+func switchBranch(repoDir, branch string, stash bool) error {
+	dirty, err := isDirty(repoDir)
+	if err != nil {
+		return err
+	}
+	stashed := ""
+	if dirty {
+		if !stash {
+			return fmt.Errorf("working tree has uncommitted changes (use --stash to stash them)")
+		}
+		if _, err := runGit(repoDir, "stash", "push", "--include-untracked", "-m", "repo-manager: check-branch --fix"); err != nil {
+			return err
+		}
+		if stashed, err = runGit(repoDir, "rev-parse", "stash@{0}"); err != nil {
+			return err
+		}
+		fmt.Printf("Stashed uncommitted changes in '%s'\n", repoDir)
+	}
+
+	err = checkoutTracking(repoDir, branch)
+	if err != nil && stashed != "" {
+		if _, popErr := runGit(repoDir, "stash", "pop"); popErr != nil {
+			return fmt.Errorf("%w; additionally failed to restore the stashed changes, apply them with git stash apply %s: %v", err, stashed, popErr)
+		}
+		fmt.Printf("Restored the stashed changes in '%s'\n", repoDir)
+	}
+	return err
+}
+
+// checkoutTracking fetches branch from origin and checks it out, creating
+// a local branch tracking origin/<branch> if needed.
+func checkoutTracking(repoDir, branch string) error {
+	if err := fetchBranch(repoDir, branch); err != nil {
+		return err
+	}
+
+	if _, err := runGit(repoDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		_, err = runGit(repoDir, "checkout", branch)
+		return err
 	}
+	_, err := runGit(repoDir, "checkout", "-b", branch, "--track", "origin/"+branch)
+	return err
 }
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupClone creates a bare origin with main and protobuf-4.x-rc branches
+// and a clone of it checked out on main. It returns the clone directory.
+func setupClone(t *testing.T) string {
+	originDir, err := os.MkdirTemp("", "origin")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(originDir) })
+	runGitT(t, originDir, "init", "--bare")
+
+	repoDir, err := os.MkdirTemp("", "repo")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(repoDir) })
+	setupGitRepo(t, repoDir)
+	createCommit(t, repoDir, "initial commit")
+	runGitT(t, repoDir, "branch", "-M", "main")
+	runGitT(t, repoDir, "remote", "add", "origin", originDir)
+	runGitT(t, repoDir, "push", "origin", "main", "main:protobuf-4.x-rc")
+	return repoDir
+}
+
+func TestSwitchBranch(t *testing.T) {
+	t.Run("creates tracking branch", func(t *testing.T) {
+		repoDir := setupClone(t)
+
+		assert.False(t, checkBranch(repoDir, "protobuf-4.x-rc"))
+		assert.NoError(t, switchBranch(repoDir, "protobuf-4.x-rc", false))
+		assert.True(t, checkBranch(repoDir, "protobuf-4.x-rc"))
+		assert.Equal(t, "origin/protobuf-4.x-rc", runGitT(t, repoDir, "rev-parse", "--abbrev-ref", "@{u}"))
+	})
+
+	t.Run("refuses dirty working tree", func(t *testing.T) {
+		repoDir := setupClone(t)
+		err := os.WriteFile(filepath.Join(repoDir, "wip.txt"), []byte("wip"), 0644)
+		assert.NoError(t, err)
+
+		assert.Error(t, switchBranch(repoDir, "protobuf-4.x-rc", false))
+		assert.True(t, checkBranch(repoDir, "main"))
+	})
+
+	t.Run("stashes dirty working tree", func(t *testing.T) {
+		repoDir := setupClone(t)
+		err := os.WriteFile(filepath.Join(repoDir, "wip.txt"), []byte("wip"), 0644)
+		assert.NoError(t, err)
+
+		assert.NoError(t, switchBranch(repoDir, "protobuf-4.x-rc", true))
+		assert.True(t, checkBranch(repoDir, "protobuf-4.x-rc"))
+		assert.Contains(t, runGitT(t, repoDir, "stash", "list"), "check-branch --fix")
+	})
+
+	t.Run("restores stash when the switch fails", func(t *testing.T) {
+		repoDir := setupClone(t)
+		err := os.WriteFile(filepath.Join(repoDir, "wip.txt"), []byte("wip"), 0644)
+		assert.NoError(t, err)
+
+		assert.Error(t, switchBranch(repoDir, "no-such-branch", true))
+		assert.True(t, checkBranch(repoDir, "main"))
+		assert.Empty(t, runGitT(t, repoDir, "stash", "list"))
+		data, err := os.ReadFile(filepath.Join(repoDir, "wip.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "wip", string(data))
+	})
+}
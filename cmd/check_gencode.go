@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var checkGencodeCmd = &cobra.Command{
+	Use:   "check-gencode",
+	Short: "Find generated protobuf code that is older than the expected protobuf major version",
+	Long: `Find generated protobuf code that is older than the expected protobuf major version.
+
+Every .java file generated by protoc is located by its "Generated by the
+protocol buffer compiler" header. Its gencode version is read from the
+"Protobuf Java Version:" header, or from the
+RuntimeVersion.validateProtobufGencodeVersion call of 4.x gencode. Files
+without either marker predate protobuf 3.25 and count as 3.x.
+
+Files are grouped by Maven module. Modules with gencode of another major
+version than --major are reported as stale, and modules mixing major
+versions as mixed. The command exits with a non-zero status if any module
+is stale or mixed, or if a repository cannot be scanned.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		names, _ := cmd.Flags().GetStringSlice("repos")
+		major, _ := cmd.Flags().GetInt("major")
+		all, _ := cmd.Flags().GetBool("all")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		repos, err = selectRepos(repos, names)
+		if err != nil {
+			fmt.Println("Error selecting repositories:", err)
+			return
+		}
+
+		results := runPool(repos, jobs, func(repo repoEntry) gencodeResult {
+			modules, err := scanGencode(repo.Dir())
+			return gencodeResult{Repo: repo, Modules: modules, Err: err}
+		})
+		if printGencodeReport(results, major, all) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkGencodeCmd)
+	checkGencodeCmd.Flags().StringSlice("repos", nil, "Only check these repositories (name or owner/name)")
+	checkGencodeCmd.Flags().Int("major", 4, "The expected protobuf major version of generated code")
+	checkGencodeCmd.Flags().Bool("all", false, "Also list modules whose generated code is up to date")
+	checkGencodeCmd.Flags().IntP("jobs", "j", 8, "Number of repositories to scan in parallel")
+}
+
+// unknownGencode is the version of generated files without a version
+// marker, which protoc only started writing in 3.25.
+const unknownGencode = "<3.25"
+
+var (
+	generatedHeaderRegexp = regexp.MustCompile(`Generated by the protocol buffer compiler`)
+	gencodeHeaderRegexp   = regexp.MustCompile(`Protobuf Java Version:\s*(\S+)`)
+	gencodeCallRegexp     = regexp.MustCompile(`validateProtobufGencodeVersion\(` +
+		`[^;]*?/\*\s*major=\s*\*/\s*(\d+),\s*/\*\s*minor=\s*\*/\s*(\d+),\s*/\*\s*patch=\s*\*/\s*(\d+),\s*/\*\s*suffix=\s*\*/\s*"([^"]*)"`)
+)
+
+// gencodeHeaderSize is how much of a file is read to find the markers.
+// protoc writes them in the header and the outer class's static
+// initializer, near the top of the file.
+const gencodeHeaderSize = 64 * 1024
+
+// gencodeVersion returns the protobuf version that generated the Java
+// source content, or unknownGencode, and whether the content was generated
+// by protoc at all.
+func gencodeVersion(content string) (string, bool) {
+	if !generatedHeaderRegexp.MatchString(content) {
+		return "", false
+	}
+	if m := gencodeHeaderRegexp.FindStringSubmatch(content); m != nil {
+		return m[1], true
+	}
+	if m := gencodeCallRegexp.FindStringSubmatch(content); m != nil {
+		v := m[1] + "." + m[2] + "." + m[3]
+		if m[4] != "" {
+			v += m[4]
+		}
+		return v, true
+	}
+	return unknownGencode, true
+}
+
+// gencodeMajor returns the major version of a gencode version, treating
+// unknownGencode as 3.
+func gencodeMajor(v string) int {
+	if v == unknownGencode {
+		return 3
+	}
+	major, _ := strconv.Atoi(strings.SplitN(v, ".", 2)[0])
+	return major
+}
+
+// gencodeModule is the generated code found in one Maven module.
+type gencodeModule struct {
+	// Path is the module directory relative to the repository root.
+	Path string
+	// Versions counts the generated files per gencode version.
+	Versions map[string]int
+}
+
+// Status returns ok, stale or mixed for the expected major version.
+func (m gencodeModule) Status(major int) string {
+	majors := make(map[int]bool)
+	for v := range m.Versions {
+		majors[gencodeMajor(v)] = true
+	}
+	switch {
+	case len(majors) > 1:
+		return "mixed"
+	case !majors[major]:
+		return "stale"
+	}
+	return "ok"
+}
+
+// gencodeResult is the outcome of scanning a single repository.
+type gencodeResult struct {
+	Repo    repoEntry
+	Modules []gencodeModule
+	Err     error
+}
+
+// scanGencode returns the generated code of repoDir grouped by Maven
+// module, sorted by path. Files outside any module are grouped under ".".
+func scanGencode(repoDir string) ([]gencodeModule, error) {
+	files, err := findFiles(repoDir, func(name string) bool { return strings.HasSuffix(name, ".java") })
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[string]gencodeModule)
+	for _, path := range files {
+		content, err := readHead(path, gencodeHeaderSize)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := gencodeVersion(content)
+		if !ok {
+			continue
+		}
+		dir := moduleDir(repoDir, path)
+		m, ok := modules[dir]
+		if !ok {
+			m = gencodeModule{Path: dir, Versions: make(map[string]int)}
+			modules[dir] = m
+		}
+		m.Versions[v]++
+	}
+
+	var list []gencodeModule
+	for _, m := range modules {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list, nil
+}
+
+// readHead returns up to n bytes from the start of the file at path.
+func readHead(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, n))
+	return string(data), err
+}
+
+// moduleDir returns the directory of the nearest pom.xml above path,
+// relative to repoDir.
+func moduleDir(repoDir, path string) string {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		rel, err := filepath.Rel(repoDir, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "."
+		}
+		if _, err := os.Stat(filepath.Join(dir, "pom.xml")); err == nil {
+			return rel
+		}
+		if rel == "." {
+			return "."
+		}
+	}
+}
+
+// printGencodeReport prints the modules that are not up to date, or all
+// modules with all set, and reports whether any module is stale or mixed or
+// any repository could not be scanned.
+func printGencodeReport(results []gencodeResult, major int, all bool) bool {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tMODULE\tFILES\tGENCODE\tSTATUS")
+	failed := false
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s\terror: %v\n", r.Repo.Dir(), r.Err)
+			failed = true
+			continue
+		}
+		for _, m := range r.Modules {
+			status := m.Status(major)
+			if status != "ok" {
+				failed = true
+			} else if !all {
+				continue
+			}
+			files := 0
+			var versions []string
+			for v, n := range m.Versions {
+				files += n
+				versions = append(versions, fmt.Sprintf("%s (%d)", v, n))
+			}
+			sort.Strings(versions)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", r.Repo.Dir(), m.Path, files, strings.Join(versions, ", "), status)
+		}
+	}
+	w.Flush()
+	if !failed {
+		fmt.Printf("All generated code is protobuf %d.x\n", major)
+	}
+	return failed
+}
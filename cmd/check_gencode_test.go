@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const gencode3 = `// Generated by the protocol buffer compiler.  DO NOT EDIT!
+// source: google/storage/v2/storage.proto
+
+package com.google.storage.v2;
+`
+
+const gencode325 = `// Generated by the protocol buffer compiler.  DO NOT EDIT!
+// source: google/storage/v2/storage.proto
+// Protobuf Java Version: 3.25.5
+
+package com.google.storage.v2;
+`
+
+const gencode4 = `// Generated by the protocol buffer compiler.  DO NOT EDIT!
+// NO CHECKED-IN PROTOBUF GENCODE
+// source: google/storage/v2/storage.proto
+
+package com.google.storage.v2;
+
+public final class StorageProto {
+  static {
+    com.google.protobuf.RuntimeVersion.validateProtobufGencodeVersion(
+        com.google.protobuf.RuntimeVersion.RuntimeDomain.PUBLIC,
+        /* major= */ 4,
+        /* minor= */ 28,
+        /* patch= */ 2,
+        /* suffix= */ "",
+        StorageProto.class.getName());
+  }
+}
+`
+
+func TestGencodeVersion(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		ok      bool
+	}{
+		{gencode3, unknownGencode, true},
+		{gencode325, "3.25.5", true},
+		{gencode4, "4.28.2", true},
+		{"package com.google.cloud;\n", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := gencodeVersion(tt.content)
+		assert.Equal(t, tt.ok, ok)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestScanGencode(t *testing.T) {
+	repoDir := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(repoDir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("pom.xml", "<project/>")
+	write("proto-google-cloud-storage-v2/pom.xml", "<project/>")
+	write("proto-google-cloud-storage-v2/src/main/java/A.java", gencode4)
+	write("proto-google-cloud-storage-v2/src/main/java/B.java", gencode4)
+	write("grpc-google-cloud-storage-v2/pom.xml", "<project/>")
+	write("grpc-google-cloud-storage-v2/src/main/java/C.java", gencode325)
+	write("grpc-google-cloud-storage-v2/src/main/java/D.java", gencode4)
+	write("google-cloud-storage/pom.xml", "<project/>")
+	write("google-cloud-storage/src/main/java/E.java", gencode3)
+	write("google-cloud-storage/src/main/java/Handwritten.java", "package com.google.cloud;\n")
+
+	modules, err := scanGencode(repoDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []gencodeModule{
+		{Path: "google-cloud-storage", Versions: map[string]int{unknownGencode: 1}},
+		{Path: "grpc-google-cloud-storage-v2", Versions: map[string]int{"3.25.5": 1, "4.28.2": 1}},
+		{Path: "proto-google-cloud-storage-v2", Versions: map[string]int{"4.28.2": 2}},
+	}, modules)
+
+	assert.Equal(t, "stale", modules[0].Status(4))
+	assert.Equal(t, "mixed", modules[1].Status(4))
+	assert.Equal(t, "ok", modules[2].Status(4))
+}
+
+func TestPrintGencodeReport(t *testing.T) {
+	repo := repoEntry{Owner: "googleapis", Name: "java-storage"}
+	ok := []gencodeModule{{Path: ".", Versions: map[string]int{"4.28.2": 1}}}
+
+	assert.False(t, printGencodeReport([]gencodeResult{{Repo: repo, Modules: ok}}, 4, false))
+	assert.True(t, printGencodeReport([]gencodeResult{{Repo: repo, Err: errors.New("permission denied")}}, 4, false))
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// marshalYAML encodes root with the two-space indentation used by the
+// release-please files, so that unchanged documents round-trip unchanged.
+func marshalYAML(root *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unifiedDiff returns a unified diff between the old and new content of
+// path.
+func unifiedDiff(path string, oldContent, newContent []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(oldContent)),
+		B:        difflib.SplitLines(string(newContent)),
+		FromFile: "a/" + path,
+		ToFile:   "b/" + path,
+		Context:  3,
+	})
+}
+
+// writeOrCheck writes content to path if it differs from the file on disk.
+// In check mode the file is left untouched and a unified diff is printed
+// instead. It reports whether the file differs.
+func writeOrCheck(path string, content []byte, check bool) (bool, error) {
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if bytes.Equal(current, content) {
+		return false, nil
+	}
+
+	if check {
+		diff, err := unifiedDiff(path, current, content)
+		if err != nil {
+			return true, err
+		}
+		fmt.Print(diff)
+		return true, nil
+	}
+	return true, os.WriteFile(path, content, 0644)
+}
+
+// reportCheck prints the repositories that would change in check mode and
+// exits with status 1 if there are any.
+func reportCheck(changed []string) {
+	if len(changed) == 0 {
+		fmt.Println("All repositories are up to date.")
+		return
+	}
+	fmt.Printf("%d repositories would change: %s\n", len(changed), strings.Join(changed, ", "))
+	os.Exit(1)
+}
@@ -4,10 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -17,18 +14,28 @@ var cleanupReleasePleaseCmd = &cobra.Command{
 	Use:   "cleanup-release-please",
 	Short: "Cleans up .github/release-please.yml files",
 	Run: func(cmd *cobra.Command, args []string) {
-		repos, err := readLines("github_repositories.txt")
+		if listRules, _ := cmd.Flags().GetBool("list-rules"); listRules {
+			printCleanupRules()
+			return
+		}
+		component, _ := cmd.Flags().GetString("component")
+		check, _ := cmd.Flags().GetBool("check")
+
+		var rules []cleanupRule
+		for _, rule := range cleanupRules {
+			if enabled, _ := cmd.Flags().GetBool(rule.name); enabled {
+				rules = append(rules, rule)
+			}
+		}
+
+		repos, err := readRepos("github_repositories.txt")
 		if err != nil {
 			log.Fatalf("Failed to read github_repositories.txt: %v", err)
 		}
 
-		for _, repoStr := range repos {
-			repoParts := strings.Split(repoStr, "/")
-			if len(repoParts) != 2 {
-				log.Printf("Skipping invalid repo format: %s", repoStr)
-				continue
-			}
-			repoName := repoParts[1]
+		var changed []string
+		for _, repo := range repos {
+			repoName := repo.Dir()
 			repoDir, err := filepath.Abs(repoName)
 			if err != nil {
 				log.Printf("Could not get absolute path for %s: %v", repoName, err)
@@ -36,145 +43,98 @@ var cleanupReleasePleaseCmd = &cobra.Command{
 			}
 
 			fmt.Printf("---" + " Cleaning up %s ---" + "\n", repoName)
-			cleanupReleasePlease(repoDir)
+			if cleanupReleasePleaseWithRules(repoDir, component, rules, check) {
+				changed = append(changed, repoName)
+			}
+		}
+
+		if check {
+			reportCheck(changed)
 		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(cleanupReleasePleaseCmd)
+	cleanupReleasePleaseCmd.Flags().String("component", "", "Only consider release tags with this component prefix, e.g. google-cloud-storage")
+	cleanupReleasePleaseCmd.Flags().Bool("check", false, "Report files that would change and print a diff without writing them")
+	cleanupReleasePleaseCmd.Flags().Bool("list-rules", false, "List the available cleanup rules and exit")
+	for _, rule := range cleanupRules {
+		cleanupReleasePleaseCmd.Flags().Bool(rule.name, rule.enabled, rule.description)
+	}
 }
 
-func isMajorRelease(tag string) bool {
-	if tag == "" {
-		return false
-	}
-	versionStr := strings.TrimPrefix(tag, "v")
-	parts := strings.Split(versionStr, ".")
-	if len(parts) > 0 {
-		major, err := strconv.Atoi(parts[0])
-		if err == nil && major >= 1 {
-			return true
+func printCleanupRules() {
+	for _, rule := range cleanupRules {
+		state := "disabled"
+		if rule.enabled {
+			state = "enabled"
 		}
+		fmt.Printf("%-22s %-9s %s\n", rule.name, state, rule.description)
 	}
-	return false
 }
 
-func getLatestTag(repoDir string) (string, error) {
-	cmd := exec.Command("git", "describe", "--tags", "--abbrev=0")
-	cmd.Dir = repoDir
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		if strings.Contains(string(output), "No names found") {
-			return "", nil // No tags, not a fatal error
-		}
-		return "", fmt.Errorf("error getting latest tag: %w, %s", err, string(output))
-	}
-	return strings.TrimSpace(string(output)), nil
+// isMajorRelease reports whether v is at or past the first major release.
+// Release candidates of 1.0.0 do not count, later pre-releases do.
+func isMajorRelease(v version) bool {
+	return v.compare(version{Major: 1}) >= 0
 }
 
-func cleanupReleasePlease(repoDir string) {
-	configPath := filepath.Join(repoDir, ".github", "release-please.yml")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		configPath = filepath.Join(repoDir, ".github", "release-please.yaml")
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			fmt.Printf("No release-please config found for %s, skipping.\n", repoDir)
-			return
-		}
+func cleanupReleasePlease(repoDir, component string) {
+	cleanupReleasePleaseWithRules(repoDir, component, defaultCleanupRules(), false)
+}
+
+// cleanupReleasePleaseWithRules applies rules, in order, to the
+// release-please.yml file of repoDir. In check mode the file is not written.
+// It reports whether the file changed or would change.
+func cleanupReleasePleaseWithRules(repoDir, component string, rules []cleanupRule, check bool) bool {
+	files := locateReleasePleaseFiles(repoDir)
+	if files.Config == "" {
+		fmt.Printf("No release-please config found for %s, skipping.\n", repoDir)
+		return false
 	}
+	configPath := files.path(files.Config)
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		log.Printf("Failed to read %s: %v", configPath, err)
-		return
+		return false
 	}
 
 	var root yaml.Node
 	err = yaml.Unmarshal(data, &root)
 	if err != nil {
 		log.Printf("Failed to unmarshal YAML from %s: %v", configPath, err)
-		return
+		return false
 	}
 
 	if len(root.Content) == 0 {
-		return // empty file
-	}
-	mainMappingNode := root.Content[0]
-
-	// Part 1: Remove redundant options
-	topLevelOptions := make(map[string]string)
-	var branchesNode *yaml.Node
-	for i := 0; i < len(mainMappingNode.Content); i += 2 {
-		keyNode := mainMappingNode.Content[i]
-		valueNode := mainMappingNode.Content[i+1]
-		if keyNode.Value == "branches" {
-			branchesNode = valueNode
-		} else {
-			topLevelOptions[keyNode.Value] = valueNode.Value
-		}
+		return false // empty file
 	}
-
-	if branchesNode != nil && branchesNode.Kind == yaml.SequenceNode {
-		for _, branchNode := range branchesNode.Content {
-			if branchNode.Kind == yaml.MappingNode {
-				var newContent []*yaml.Node
-				for i := 0; i < len(branchNode.Content); i += 2 {
-					keyNode := branchNode.Content[i]
-					valueNode := branchNode.Content[i+1]
-					if topValue, ok := topLevelOptions[keyNode.Value]; ok && topValue == valueNode.Value {
-						fmt.Printf("  - Removing redundant option '%s' from branch\n", keyNode.Value)
-					} else {
-						newContent = append(newContent, keyNode, valueNode)
-					}
-				}
-				branchNode.Content = newContent
-			}
-		}
+	c := &cleanupContext{repoDir: repoDir, component: component, config: root.Content[0]}
+	for _, rule := range rules {
+		rule.apply(c)
 	}
 
-	// Part 2: Remove bump-minor-pre-major for major releases
-	latestTag, err := getLatestTag(repoDir)
-	if err != nil {
-		log.Printf("  - Could not get latest tag for %s: %v. Skipping bump-minor-pre-major check.", repoDir, err)
-	} else if isMajorRelease(latestTag) {
-		fmt.Printf("  - Repo is at major release (%s). Removing 'bump-minor-pre-major'.\n", latestTag)
-
-		var newMainContent []*yaml.Node
-		for i := 0; i < len(mainMappingNode.Content); i += 2 {
-			keyNode := mainMappingNode.Content[i]
-			if keyNode.Value != "bump-minor-pre-major" {
-				newMainContent = append(newMainContent, keyNode, mainMappingNode.Content[i+1])
-			}
-		}
-		mainMappingNode.Content = newMainContent
-
-		if branchesNode != nil && branchesNode.Kind == yaml.SequenceNode {
-			for _, branchNode := range branchesNode.Content {
-				if branchNode.Kind == yaml.MappingNode {
-					var newBranchContent []*yaml.Node
-					for i := 0; i < len(branchNode.Content); i += 2 {
-						keyNode := branchNode.Content[i]
-						if keyNode.Value != "bump-minor-pre-major" {
-							newBranchContent = append(newBranchContent, keyNode, branchNode.Content[i+1])
-						}
-					}
-					branchNode.Content = newBranchContent
-				}
-			}
-		}
-	}
-
-	marshaledData, err := yaml.Marshal(&root)
+	marshaledData, err := marshalYAML(&root)
 	if err != nil {
 		log.Printf("Failed to marshal YAML for %s: %v", configPath, err)
-		return
+		return false
 	}
 
-	err = os.WriteFile(configPath, marshaledData, 0644)
+	changed, err := writeOrCheck(configPath, marshaledData, check)
 	if err != nil {
 		log.Printf("Failed to write %s: %v", configPath, err)
-		return
+		return false
 	}
 
-	fmt.Printf("Successfully cleaned up release-please config for %s\n", repoDir)
+	switch {
+	case !changed:
+		fmt.Printf("Release-please config for %s is already clean\n", repoDir)
+	case check:
+		fmt.Printf("Release-please config for %s would be cleaned up\n", repoDir)
+	default:
+		fmt.Printf("Successfully cleaned up release-please config for %s\n", repoDir)
+	}
+	return changed
 }
@@ -72,7 +72,7 @@ branches:
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		cleanupReleasePlease(repoDir)
+		cleanupReleasePlease(repoDir, "")
 
 		data, err := os.ReadFile(configPath)
 		assert.NoError(t, err)
@@ -106,7 +106,7 @@ bump-minor-pre-major: true
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		cleanupReleasePlease(repoDir)
+		cleanupReleasePlease(repoDir, "")
 
 		data, err := os.ReadFile(configPath)
 		assert.NoError(t, err)
@@ -130,7 +130,7 @@ bump-minor-pre-major: true
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		cleanupReleasePlease(repoDir)
+		cleanupReleasePlease(repoDir, "")
 
 		data, err := os.ReadFile(configPath)
 		assert.NoError(t, err)
@@ -144,7 +144,7 @@ bump-minor-pre-major: true
 		setupGitRepo(t, repoDir)
 
 		// No config file created
-		cleanupReleasePlease(repoDir)
+		cleanupReleasePlease(repoDir, "")
 		// No assertion, just checking for no panic
 	})
 
@@ -164,7 +164,7 @@ bump-minor-pre-major: true
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		cleanupReleasePlease(repoDir)
+		cleanupReleasePlease(repoDir, "")
 
 		data, err := os.ReadFile(configPath)
 		assert.NoError(t, err)
@@ -188,7 +188,7 @@ bump-minor-pre-major: true
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		cleanupReleasePlease(repoDir)
+		cleanupReleasePlease(repoDir, "")
 
 		data, err := os.ReadFile(configPath)
 		assert.NoError(t, err)
@@ -207,7 +207,126 @@ bump-minor-pre-major: true
 		err = os.WriteFile(configPath, []byte(""), 0644)
 		assert.NoError(t, err)
 
-		cleanupReleasePlease(repoDir)
+		cleanupReleasePlease(repoDir, "")
 		// No assertion, just checking for no panic
 	})
+
+	t.Run("uses component tags", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+		setupGitRepo(t, repoDir)
+		createTag(t, repoDir, "google-cloud-storage-v2.1.0")
+		createTag(t, repoDir, "google-cloud-storage-nio-v0.3.0")
+
+		config := `
+release-type: simple
+bump-minor-pre-major: true
+`
+		githubDir := filepath.Join(repoDir, ".github")
+		os.Mkdir(githubDir, 0755)
+		configPath := filepath.Join(githubDir, "release-please.yml")
+		err = os.WriteFile(configPath, []byte(config), 0644)
+		assert.NoError(t, err)
+
+		cleanupReleasePlease(repoDir, "google-cloud-storage-nio")
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "bump-minor-pre-major")
+
+		cleanupReleasePlease(repoDir, "google-cloud-storage")
+		data, err = os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "bump-minor-pre-major")
+	})
+
+	t.Run("check mode fetches tags of shallow clones", func(t *testing.T) {
+		originDir, err := os.MkdirTemp("", "origin")
+		assert.NoError(t, err)
+		defer os.RemoveAll(originDir)
+		setupGitRepo(t, originDir)
+		config := `
+release-type: simple
+bump-minor-pre-major: true
+`
+		githubDir := filepath.Join(originDir, ".github")
+		os.Mkdir(githubDir, 0755)
+		err = os.WriteFile(filepath.Join(githubDir, "release-please.yml"), []byte(config), 0644)
+		assert.NoError(t, err)
+		createTag(t, originDir, "v1.0.0")
+		createCommit(t, originDir, "after release")
+
+		// The tag is not on the only commit of the shallow clone.
+		repoDir := filepath.Join(t.TempDir(), "repo")
+		cmd := exec.Command("git", "clone", "--depth=1", "file://"+originDir, repoDir)
+		assert.NoError(t, cmd.Run())
+		assert.Empty(t, runGitT(t, repoDir, "tag", "--list"))
+
+		changed := cleanupReleasePleaseWithRules(repoDir, "", defaultCleanupRules(), true)
+		assert.True(t, changed)
+		data, err := os.ReadFile(filepath.Join(repoDir, ".github", "release-please.yml"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "bump-minor-pre-major")
+	})
+
+	t.Run("falls back to manifest version", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+		setupGitRepo(t, repoDir)
+
+		config := `
+release-type: simple
+bump-minor-pre-major: true
+`
+		githubDir := filepath.Join(repoDir, ".github")
+		os.Mkdir(githubDir, 0755)
+		configPath := filepath.Join(githubDir, "release-please.yml")
+		err = os.WriteFile(configPath, []byte(config), 0644)
+		assert.NoError(t, err)
+		err = os.WriteFile(filepath.Join(repoDir, ".release-please-manifest.json"), []byte(`{".": "2.50.0-rc1"}`), 0644)
+		assert.NoError(t, err)
+
+		cleanupReleasePlease(repoDir, "")
+
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "bump-minor-pre-major")
+	})
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag       string
+		component string
+		version   string
+	}{
+		{"v1.2.3", "", "1.2.3"},
+		{"1.2.3", "", "1.2.3"},
+		{"v1.2.3-rc1", "", "1.2.3-rc1"},
+		{"google-cloud-storage-v2.1.0", "google-cloud-storage", "2.1.0"},
+		{"google-cloud-storage-nio-v0.3.0-alpha", "google-cloud-storage-nio", "0.3.0-alpha"},
+	}
+	for _, tt := range tests {
+		component, v, err := parseTag(tt.tag)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.component, component, tt.tag)
+		assert.Equal(t, tt.version, v.String(), tt.tag)
+	}
+
+	_, _, err := parseTag("release-candidate")
+	assert.Error(t, err)
+}
+
+func TestIsMajorRelease(t *testing.T) {
+	for s, want := range map[string]bool{
+		"0.9.0":      false,
+		"1.0.0-rc1":  false,
+		"1.0.0":      true,
+		"2.50.0-rc1": true,
+	} {
+		v, err := parseVersion(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, isMajorRelease(v), s)
+	}
 }
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// cleanupContext is the state shared by cleanup rules while processing a
+// single release-please.yml file.
+type cleanupContext struct {
+	repoDir   string
+	component string
+	// config is the top-level mapping node of the file.
+	config *yaml.Node
+}
+
+// branches returns the mapping nodes of the "branches" sequence.
+func (c *cleanupContext) branches() []*yaml.Node {
+	branchesNode := mappingValue(c.config, "branches")
+	if branchesNode == nil || branchesNode.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var branches []*yaml.Node
+	for _, branchNode := range branchesNode.Content {
+		if branchNode.Kind == yaml.MappingNode {
+			branches = append(branches, branchNode)
+		}
+	}
+	return branches
+}
+
+// setBranches replaces the "branches" sequence content.
+func (c *cleanupContext) setBranches(branches []*yaml.Node) {
+	if branchesNode := mappingValue(c.config, "branches"); branchesNode != nil {
+		branchesNode.Content = branches
+	}
+}
+
+// cleanupRule is a named transform applied to a release-please.yml file.
+type cleanupRule struct {
+	name        string
+	description string
+	// enabled is the default used when the rule's flag is not given.
+	enabled bool
+	apply   func(c *cleanupContext)
+}
+
+// cleanupRules lists every rule in the order it is applied. Each rule gets
+// a boolean flag of the same name on cleanup-release-please.
+var cleanupRules = []cleanupRule{
+	{
+		name:        "normalize-keys",
+		description: "Rename kebab-case options such as bump-minor-pre-major to camelCase",
+		enabled:     false,
+		apply:       normalizeKeysRule,
+	},
+	{
+		name:        "duplicate-branches",
+		description: "Remove branch entries that repeat an earlier entry's branch",
+		enabled:     true,
+		apply:       duplicateBranchesRule,
+	},
+	{
+		name:        "stale-branches",
+		description: "Remove branch entries for branches that no longer exist on origin",
+		enabled:     false,
+		apply:       staleBranchesRule,
+	},
+	{
+		name:        "redundant-options",
+		description: "Remove branch options that are equal to the top-level option",
+		enabled:     true,
+		apply:       redundantOptionsRule,
+	},
+	{
+		name:        "bump-minor-pre-major",
+		description: "Remove bump-minor-pre-major once the repository is past 1.0",
+		enabled:     true,
+		apply:       bumpMinorPreMajorRule,
+	},
+}
+
+// defaultCleanupRules returns the rules that are enabled by default.
+func defaultCleanupRules() []cleanupRule {
+	var rules []cleanupRule
+	for _, rule := range cleanupRules {
+		if rule.enabled {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// camelCaseKeyExceptions holds options whose camelCase form does not follow
+// from simple word capitalization.
+var camelCaseKeyExceptions = map[string]string{
+	"handle-gh-release": "handleGHRelease",
+}
+
+// camelCaseKey converts a kebab-case option name to camelCase. Names that
+// are already camelCase are returned unchanged.
+func camelCaseKey(key string) string {
+	if camel, ok := camelCaseKeyExceptions[key]; ok {
+		return camel
+	}
+	parts := strings.Split(key, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKeys drops every key for which remove returns true.
+func removeMappingKeys(mapping *yaml.Node, remove func(key string) bool) {
+	var newContent []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if !remove(mapping.Content[i].Value) {
+			newContent = append(newContent, mapping.Content[i], mapping.Content[i+1])
+		}
+	}
+	mapping.Content = newContent
+}
+
+// nodesEqual reports whether two nodes hold the same YAML value.
+func nodesEqual(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !nodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeKeys(mapping *yaml.Node, where string) {
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		seen[mapping.Content[i].Value] = true
+	}
+	removeMappingKeys(mapping, func(key string) bool {
+		camel := camelCaseKey(key)
+		if camel == key {
+			return false
+		}
+		if seen[camel] {
+			fmt.Printf("  - Removing '%s' from %s, '%s' is already set\n", key, where, camel)
+			return true
+		}
+		return false
+	})
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode := mapping.Content[i]
+		if camel := camelCaseKey(keyNode.Value); camel != keyNode.Value {
+			fmt.Printf("  - Renaming '%s' to '%s' in %s\n", keyNode.Value, camel, where)
+			keyNode.Value = camel
+		}
+	}
+}
+
+func normalizeKeysRule(c *cleanupContext) {
+	normalizeKeys(c.config, "top level")
+	for _, branchNode := range c.branches() {
+		normalizeKeys(branchNode, "branch")
+	}
+}
+
+func duplicateBranchesRule(c *cleanupContext) {
+	if c.branches() == nil {
+		return
+	}
+	seen := make(map[string]bool)
+	var kept []*yaml.Node
+	for _, branchNode := range mappingValue(c.config, "branches").Content {
+		if branchNode.Kind == yaml.MappingNode {
+			if nameNode := mappingValue(branchNode, "branch"); nameNode != nil {
+				if seen[nameNode.Value] {
+					fmt.Printf("  - Removing duplicate entry for branch '%s'\n", nameNode.Value)
+					continue
+				}
+				seen[nameNode.Value] = true
+			}
+		}
+		kept = append(kept, branchNode)
+	}
+	c.setBranches(kept)
+}
+
+// remoteBranchExists checks origin for a branch with git ls-remote.
+func remoteBranchExists(repoDir, branch string) (bool, error) {
+	cmd := exec.Command("git", "ls-remote", "--exit-code", "--heads", "origin", branch)
+	cmd.Dir = repoDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
+			return false, nil
+		}
+		return false, fmt.Errorf("error listing remote branch %s: %w, %s", branch, err, string(output))
+	}
+	return true, nil
+}
+
+func staleBranchesRule(c *cleanupContext) {
+	if c.branches() == nil {
+		return
+	}
+	var kept []*yaml.Node
+	for _, branchNode := range mappingValue(c.config, "branches").Content {
+		if branchNode.Kind == yaml.MappingNode {
+			if nameNode := mappingValue(branchNode, "branch"); nameNode != nil {
+				exists, err := remoteBranchExists(c.repoDir, nameNode.Value)
+				if err != nil {
+					log.Printf("  - Could not check branch '%s': %v. Keeping it.", nameNode.Value, err)
+				} else if !exists {
+					fmt.Printf("  - Removing entry for deleted branch '%s'\n", nameNode.Value)
+					continue
+				}
+			}
+		}
+		kept = append(kept, branchNode)
+	}
+	c.setBranches(kept)
+}
+
+func redundantOptionsRule(c *cleanupContext) {
+	topLevelOptions := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(c.config.Content); i += 2 {
+		if key := c.config.Content[i].Value; key != "branches" {
+			topLevelOptions[camelCaseKey(key)] = c.config.Content[i+1]
+		}
+	}
+
+	for _, branchNode := range c.branches() {
+		var newContent []*yaml.Node
+		for i := 0; i+1 < len(branchNode.Content); i += 2 {
+			keyNode := branchNode.Content[i]
+			valueNode := branchNode.Content[i+1]
+			if topValue, ok := topLevelOptions[camelCaseKey(keyNode.Value)]; ok && nodesEqual(topValue, valueNode) {
+				fmt.Printf("  - Removing redundant option '%s' from branch\n", keyNode.Value)
+			} else {
+				newContent = append(newContent, keyNode, valueNode)
+			}
+		}
+		branchNode.Content = newContent
+	}
+}
+
+func bumpMinorPreMajorRule(c *cleanupContext) {
+	latestVersion, source, err := getLatestVersion(c.repoDir, c.component)
+	if err != nil {
+		log.Printf("  - Could not get latest version for %s: %v. Skipping bump-minor-pre-major check.", c.repoDir, err)
+		return
+	}
+	if source == "" {
+		fmt.Println("  - No release tag or manifest version found. Skipping bump-minor-pre-major check.")
+		return
+	}
+	if !isMajorRelease(latestVersion) {
+		return
+	}
+	fmt.Printf("  - Repo is at major release (%s from %s). Removing 'bump-minor-pre-major'.\n", latestVersion, source)
+
+	isBumpMinorPreMajor := func(key string) bool {
+		return camelCaseKey(key) == "bumpMinorPreMajor"
+	}
+	removeMappingKeys(c.config, isBumpMinorPreMajor)
+	for _, branchNode := range c.branches() {
+		removeMappingKeys(branchNode, isBumpMinorPreMajor)
+	}
+}
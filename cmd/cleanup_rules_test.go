@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeReleasePleaseConfig(t *testing.T, repoDir, config string) string {
+	githubDir := filepath.Join(repoDir, ".github")
+	os.Mkdir(githubDir, 0755)
+	configPath := filepath.Join(githubDir, "release-please.yml")
+	err := os.WriteFile(configPath, []byte(config), 0644)
+	assert.NoError(t, err)
+	return configPath
+}
+
+func findCleanupRules(names ...string) []cleanupRule {
+	var rules []cleanupRule
+	for _, name := range names {
+		for _, rule := range cleanupRules {
+			if rule.name == name {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+func TestCamelCaseKey(t *testing.T) {
+	assert.Equal(t, "bumpMinorPreMajor", camelCaseKey("bump-minor-pre-major"))
+	assert.Equal(t, "releaseType", camelCaseKey("releaseType"))
+	assert.Equal(t, "handleGHRelease", camelCaseKey("handle-gh-release"))
+}
+
+func TestCleanupRules(t *testing.T) {
+	t.Run("normalize-keys renames kebab-case options", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		configPath := writeReleasePleaseConfig(t, repoDir, `
+release-type: java-yoshi
+releaseType: java-yoshi
+branches:
+  - branch: 1.0.x
+    bump-minor-pre-major: true
+`)
+		cleanupReleasePleaseWithRules(repoDir, "", findCleanupRules("normalize-keys"), false)
+
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "releaseType: java-yoshi\nbranches:\n  - branch: 1.0.x\n    bumpMinorPreMajor: true\n", string(data))
+	})
+
+	t.Run("duplicate-branches keeps the first entry", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		configPath := writeReleasePleaseConfig(t, repoDir, `
+branches:
+  - branch: 1.0.x
+    releaseType: java-backport
+  - branch: 2.0.x
+  - branch: 1.0.x
+    releaseType: java-yoshi
+`)
+		cleanupReleasePleaseWithRules(repoDir, "", findCleanupRules("duplicate-branches"), false)
+
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "java-backport")
+		assert.NotContains(t, string(data), "java-yoshi")
+		assert.Contains(t, string(data), "2.0.x")
+	})
+
+	t.Run("redundant-options compares whole values", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		configPath := writeReleasePleaseConfig(t, repoDir, `
+releaseType: java-yoshi
+extraFiles:
+  - README.md
+branches:
+  - branch: 1.0.x
+    release-type: java-yoshi
+    extraFiles:
+      - CHANGELOG.md
+`)
+		cleanupReleasePleaseWithRules(repoDir, "", findCleanupRules("redundant-options"), false)
+
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "release-type")
+		assert.Contains(t, string(data), "CHANGELOG.md")
+	})
+
+	t.Run("stale-branches removes branches missing on origin", func(t *testing.T) {
+		originDir, err := os.MkdirTemp("", "origin")
+		assert.NoError(t, err)
+		defer os.RemoveAll(originDir)
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		assert.NoError(t, exec.Command("git", "init", "--bare", originDir).Run())
+		setupGitRepo(t, repoDir)
+		createCommit(t, repoDir, "initial commit")
+		for _, args := range [][]string{
+			{"branch", "1.0.x"},
+			{"remote", "add", "origin", originDir},
+			{"push", "origin", "1.0.x"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = repoDir
+			assert.NoError(t, cmd.Run())
+		}
+
+		configPath := writeReleasePleaseConfig(t, repoDir, `
+branches:
+  - branch: 1.0.x
+  - branch: 0.9.x
+`)
+		cleanupReleasePleaseWithRules(repoDir, "", findCleanupRules("stale-branches"), false)
+
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "1.0.x")
+		assert.NotContains(t, string(data), "0.9.x")
+	})
+
+	t.Run("bump-minor-pre-major handles camelCase", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+		setupGitRepo(t, repoDir)
+		createTag(t, repoDir, "v1.0.0")
+
+		configPath := writeReleasePleaseConfig(t, repoDir, `
+releaseType: java-yoshi
+bumpMinorPreMajor: true
+`)
+		cleanupReleasePleaseWithRules(repoDir, "", findCleanupRules("bump-minor-pre-major"), false)
+
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "bumpMinorPreMajor")
+	})
+}
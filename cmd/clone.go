@@ -15,17 +15,19 @@ var cloneCmd = &cobra.Command{
 	Short: "Clone repositories from a file",
 	Run: func(cmd *cobra.Command, args []string) {
 		branch, _ := cmd.Flags().GetString("branch")
-		cloneRepos(branch)
+		resume, _ := cmd.Flags().GetString("resume")
+		cloneRepos(branch, resume)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(cloneCmd)
 	cloneCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "Branch to clone")
+	cloneCmd.Flags().String("resume", "", "Resume an interrupted run, skipping repositories already cloned")
 }
 
-func cloneRepos(branch string) {
-	repos, err := readLines("github_repositories.txt")
+func cloneRepos(branch, resume string) {
+	repos, err := readRepos("github_repositories.txt")
 	if err != nil {
 		fmt.Println("Error reading repositories file:", err)
 		return
@@ -43,12 +45,20 @@ func cloneRepos(branch string) {
 		return
 	}
 
+	journal, err := startRun("clone", resume)
+	if err != nil {
+		fmt.Println("Error starting run:", err)
+		return
+	}
+
 	var wg sync.WaitGroup
 	for _, repo := range repos {
 		wg.Add(1)
-		go func(repo string) {
+		go func(repo repoEntry) {
 			defer wg.Done()
-			cloneRepo(repo, token, branch)
+			journal.Step(repo.FullName(), "clone", func() error {
+				return cloneRepo(repo.FullName(), token, repo.BranchOr(branch))
+			})
 		}(repo)
 	}
 
@@ -56,13 +66,14 @@ func cloneRepos(branch string) {
 	fmt.Println("All repositories cloned.")
 }
 
-func cloneRepo(repo, token, branch string) {
+func cloneRepo(repo, token, branch string) error {
 	url := fmt.Sprintf("https://%s@github.com/%s.git", token, repo)
 	cmd := exec.Command("git", "clone", "--branch", branch, "--depth", "1", url)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		fmt.Printf("Error cloning %s: %s\n%s", repo, err, output)
-		return
+		return err
 	}
 	fmt.Printf("Successfully cloned %s\n%s", repo, output)
+	return nil
 }
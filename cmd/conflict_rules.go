@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolutionRule resolves merge conflicts in files matching Path.
+//
+// Path is a glob. Patterns without a slash match the file name in any
+// directory, others match the full path relative to the repository root.
+// Strategy is one of:
+//
+//	ours        keep the updated branch's side of each conflicting hunk
+//	theirs      keep the incoming branch's side of each conflicting hunk
+//	union       keep both sides of each conflicting hunk
+//	versions    keep ours where the sides differ only in version numbers,
+//	            and leave the file conflicting otherwise
+//	regenerate  resolve as ours, then run Command to rewrite the file
+//
+// "ours" always refers to the branch being updated, also when rebasing.
+type resolutionRule struct {
+	Path     string `yaml:"path"`
+	Strategy string `yaml:"strategy"`
+	// Command is run with sh -c in the repository for the regenerate
+	// strategy. The conflicting path is in $CONFLICT_PATH.
+	Command string `yaml:"command,omitempty"`
+}
+
+// defaultResolutionRules covers the files that routinely conflict when
+// syncing main into the release candidate branch. POMs and READMEs only keep
+// the release candidate's versions; any other change in a conflicting hunk
+// needs a person.
+var defaultResolutionRules = []resolutionRule{
+	{Path: "versions.txt", Strategy: "ours"},
+	{Path: ".release-please-manifest.json", Strategy: "ours"},
+	{Path: "CHANGELOG.md", Strategy: "union"},
+	{Path: "pom.xml", Strategy: "versions"},
+	{Path: "README.md", Strategy: "versions"},
+}
+
+// resolutionRulesFile is the file rules are read from, in the format
+//
+//	rules:
+//	  - path: CHANGELOG.md
+//	    strategy: union
+type resolutionRulesFile struct {
+	Rules []resolutionRule `yaml:"rules"`
+}
+
+// readResolutionRules reads rules from a YAML file and validates them.
+func readResolutionRules(filename string) ([]resolutionRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file resolutionRulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	for _, rule := range file.Rules {
+		switch rule.Strategy {
+		case "ours", "theirs", "union", "versions":
+		case "regenerate":
+			if rule.Command == "" {
+				return nil, fmt.Errorf("rule for %s: regenerate needs a command", rule.Path)
+			}
+		default:
+			return nil, fmt.Errorf("rule for %s: unknown strategy %q", rule.Path, rule.Strategy)
+		}
+	}
+	return file.Rules, nil
+}
+
+// matchResolutionRule returns the first rule matching file, or nil.
+func matchResolutionRule(rules []resolutionRule, file string) *resolutionRule {
+	for i, rule := range rules {
+		name := file
+		if !strings.Contains(rule.Path, "/") {
+			name = path.Base(file)
+		}
+		if ok, _ := path.Match(rule.Path, name); ok {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// resolveConflicts applies rules to the conflicting files and stages the
+// ones it resolves. rebasing swaps the index stages so that "ours" keeps
+// the branch being updated. It returns the files that are still conflicting.
+func resolveConflicts(repoDir string, conflicts []string, rules []resolutionRule, rebasing bool) []string {
+	var remaining []string
+	for _, file := range conflicts {
+		rule := matchResolutionRule(rules, file)
+		if rule == nil {
+			remaining = append(remaining, file)
+			continue
+		}
+		if err := resolveConflict(repoDir, file, *rule, rebasing); err != nil {
+			fmt.Printf("  - Could not resolve %s with %s: %v\n", file, rule.Strategy, err)
+			remaining = append(remaining, file)
+			continue
+		}
+		fmt.Printf("  - Resolved %s with %s\n", file, rule.Strategy)
+	}
+	return remaining
+}
+
+func resolveConflict(repoDir, file string, rule resolutionRule, rebasing bool) error {
+	favor := rule.Strategy
+	if favor == "regenerate" {
+		favor = "ours"
+	}
+	if rebasing {
+		switch favor {
+		case "ours":
+			favor = "theirs"
+		case "theirs":
+			favor = "ours"
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "conflict")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Stage 1 is the merge base, 2 is HEAD and 3 is the incoming side. The
+	// base is missing when both sides added the file.
+	stages := make([]string, 3)
+	for i := range stages {
+		stages[i] = filepath.Join(tmpDir, fmt.Sprintf("stage%d", i+1))
+		content, err := exec.Command("git", "-C", repoDir, "show", fmt.Sprintf(":%d:%s", i+1, file)).Output()
+		if err != nil {
+			if i == 0 {
+				content = nil
+			} else {
+				return fmt.Errorf("%s was deleted on one side", file)
+			}
+		}
+		if err := os.WriteFile(stages[i], content, 0644); err != nil {
+			return err
+		}
+	}
+
+	var merged []byte
+	if rule.Strategy == "versions" {
+		merged, err = mergeVersionConflicts(stages, rebasing)
+	} else {
+		merged, err = exec.Command("git", "merge-file", "-p", "--"+favor, stages[1], stages[0], stages[2]).Output()
+		if err != nil {
+			err = fmt.Errorf("git merge-file: %w", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	mode, err := conflictFileMode(repoDir, file)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(repoDir, file)
+	if err := os.WriteFile(target, merged, mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, mode); err != nil {
+		return err
+	}
+
+	if rule.Strategy == "regenerate" {
+		cmd := exec.Command("sh", "-c", rule.Command)
+		cmd.Dir = repoDir
+		cmd.Env = append(os.Environ(), "CONFLICT_PATH="+file)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w\n%s", rule.Command, err, output)
+		}
+	}
+
+	_, err = runGit(repoDir, "add", "--", file)
+	return err
+}
+
+// conflictFileMode returns the permissions of HEAD's side of a conflicting
+// file, so that resolving it keeps executable scripts executable.
+func conflictFileMode(repoDir, file string) (os.FileMode, error) {
+	output, err := runGit(repoDir, "ls-files", "--stage", "--", file)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[2] == "2" && fields[0] == "100755" {
+			return 0755, nil
+		}
+	}
+	return 0644, nil
+}
+
+// versionPattern matches version numbers such as 2.50.0, 2.50.0-rc1 or
+// 2.50.1-SNAPSHOT.
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+(-[0-9A-Za-z.]+)*`)
+
+// mergeVersionConflicts merges the stage files of a conflicting file and
+// resolves every conflicting hunk whose sides differ only in version
+// numbers by keeping the branch being updated, which is stage 3 instead of
+// stage 2 when rebasing. Any other conflicting hunk is an error.
+func mergeVersionConflicts(stages []string, rebasing bool) ([]byte, error) {
+	merged, err := exec.Command("git", "merge-file", "-p", stages[1], stages[0], stages[2]).Output()
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() > 127) {
+		return nil, fmt.Errorf("git merge-file: %w", err)
+	}
+
+	var out, ours, theirs []string
+	// section is 0 outside a conflict, then 1 for the HEAD side, 3 for the
+	// merge base in the diff3 style, and 2 for the incoming side.
+	section := 0
+	for _, line := range strings.SplitAfter(string(merged), "\n") {
+		switch {
+		case section == 0 && strings.HasPrefix(line, "<<<<<<< "):
+			section = 1
+		case section == 1 && strings.HasPrefix(line, "|||||||"):
+			section = 3
+		case (section == 1 || section == 3) && strings.HasPrefix(line, "======="):
+			section = 2
+		case section == 2 && strings.HasPrefix(line, ">>>>>>> "):
+			if !sameButVersions(ours, theirs) {
+				return nil, fmt.Errorf("a conflict is not only about versions")
+			}
+			if rebasing {
+				out = append(out, theirs...)
+			} else {
+				out = append(out, ours...)
+			}
+			ours, theirs, section = nil, nil, 0
+		case section == 1:
+			ours = append(ours, line)
+		case section == 2:
+			theirs = append(theirs, line)
+		case section == 3:
+		default:
+			out = append(out, line)
+		}
+	}
+	if section != 0 {
+		return nil, fmt.Errorf("unterminated conflict")
+	}
+	return []byte(strings.Join(out, "")), nil
+}
+
+// sameButVersions reports whether a and b are the same lines once version
+// numbers are ignored.
+func sameButVersions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if versionPattern.ReplaceAllString(a[i], "") != versionPattern.ReplaceAllString(b[i], "") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchResolutionRule(t *testing.T) {
+	rules := []resolutionRule{
+		{Path: "pom.xml", Strategy: "ours"},
+		{Path: "docs/*.md", Strategy: "theirs"},
+		{Path: "*.md", Strategy: "union"},
+	}
+	assert.Equal(t, "ours", matchResolutionRule(rules, "google-cloud-storage/pom.xml").Strategy)
+	assert.Equal(t, "theirs", matchResolutionRule(rules, "docs/index.md").Strategy)
+	assert.Equal(t, "union", matchResolutionRule(rules, "samples/README.md").Strategy)
+	assert.Nil(t, matchResolutionRule(rules, "src/Main.java"))
+}
+
+func TestReadResolutionRules(t *testing.T) {
+	dir, err := os.MkdirTemp("", "rules")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "conflict-rules.yaml")
+	err = os.WriteFile(path, []byte(`
+rules:
+  - path: versions.txt
+    strategy: ours
+  - path: pom.xml
+    strategy: regenerate
+    command: ./regen.sh "$CONFLICT_PATH"
+`), 0644)
+	assert.NoError(t, err)
+
+	rules, err := readResolutionRules(path)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, `./regen.sh "$CONFLICT_PATH"`, rules[1].Command)
+
+	err = os.WriteFile(path, []byte("rules:\n  - path: pom.xml\n    strategy: regenerate\n"), 0644)
+	assert.NoError(t, err)
+	_, err = readResolutionRules(path)
+	assert.Error(t, err)
+}
+
+func TestUpdateBranchResolvesConflicts(t *testing.T) {
+	rules := []resolutionRule{
+		{Path: "versions.txt", Strategy: "ours"},
+		{Path: "CHANGELOG.md", Strategy: "union"},
+	}
+
+	for _, strategy := range []string{"merge", "rebase", "squash"} {
+		t.Run(strategy, func(t *testing.T) {
+			repoDir := setupClone(t)
+			commitFile(t, repoDir, "versions.txt", "storage:2.50.0:2.50.0\n")
+			commitFile(t, repoDir, "CHANGELOG.md", "# Changelog\n")
+			runGitT(t, repoDir, "push", "origin", "main:main", "main:protobuf-4.x-rc")
+
+			commitFile(t, repoDir, "versions.txt", "storage:2.51.0:2.51.0\n")
+			commitFile(t, repoDir, "CHANGELOG.md", "# Changelog\n2.51.0\n")
+			runGitT(t, repoDir, "push", "origin", "main")
+
+			runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+			commitFile(t, repoDir, "versions.txt", "storage:2.51.0-rc1:2.51.0-rc1\n")
+			commitFile(t, repoDir, "CHANGELOG.md", "# Changelog\n2.51.0-rc1\n")
+			runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+
+			// The rebased branch cannot be pushed without --pr, so check
+			// the local result of the update instead.
+			updated := "origin/protobuf-4.x-rc"
+			if strategy == "rebase" {
+				conflicts, err := applyUpdate(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: strategy, Rules: rules})
+				assert.NoError(t, err)
+				assert.Empty(t, conflicts)
+				updated = "HEAD"
+			} else {
+				result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: strategy, Rules: rules})
+				assert.NoError(t, result.Err)
+				assert.Empty(t, result.Conflicts)
+				assert.True(t, result.Updated)
+			}
+
+			assert.Equal(t, "storage:2.51.0-rc1:2.51.0-rc1", runGitT(t, repoDir, "show", updated+":versions.txt"))
+			changelog := runGitT(t, repoDir, "show", updated+":CHANGELOG.md")
+			assert.ElementsMatch(t, []string{"# Changelog", "2.51.0-rc1", "2.51.0"}, strings.Split(changelog, "\n"))
+		})
+	}
+}
+
+func TestResolveConflictKeepsMode(t *testing.T) {
+	repoDir := setupClone(t)
+	script := filepath.Join(repoDir, "generate.sh")
+	commitScript := func(content string) {
+		assert.NoError(t, os.WriteFile(script, []byte(content), 0755))
+		runGitT(t, repoDir, "add", "generate.sh")
+		runGitT(t, repoDir, "commit", "-m", "update generate.sh")
+	}
+	commitScript("#!/bin/sh\necho base\n")
+	runGitT(t, repoDir, "push", "origin", "main:main", "main:protobuf-4.x-rc")
+	commitScript("#!/bin/sh\necho main\n")
+	runGitT(t, repoDir, "push", "origin", "main")
+	runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+	commitScript("#!/bin/sh\necho rc\n")
+	runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+
+	rules := []resolutionRule{{Path: "generate.sh", Strategy: "ours"}}
+	result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge", Rules: rules})
+	assert.NoError(t, result.Err)
+	assert.True(t, result.Updated)
+
+	info, err := os.Stat(script)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	assert.Contains(t, runGitT(t, repoDir, "ls-files", "--stage", "generate.sh"), "100755")
+}
+
+func TestResolveVersionConflicts(t *testing.T) {
+	pom := func(version, dependency string) string {
+		return "<project>\n  <version>" + version + "</version>\n  <dependency>" + dependency + "</dependency>\n</project>\n"
+	}
+	setup := func(t *testing.T, rc string) string {
+		repoDir := setupClone(t)
+		commitFile(t, repoDir, "pom.xml", pom("2.50.0", "gax"))
+		runGitT(t, repoDir, "push", "origin", "main:main", "main:protobuf-4.x-rc")
+		commitFile(t, repoDir, "pom.xml", pom("2.51.0-SNAPSHOT", "gax"))
+		runGitT(t, repoDir, "push", "origin", "main")
+		runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+		commitFile(t, repoDir, "pom.xml", rc)
+		runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+		return repoDir
+	}
+	rules := []resolutionRule{{Path: "pom.xml", Strategy: "versions"}}
+
+	t.Run("keeps the branch's versions", func(t *testing.T) {
+		repoDir := setup(t, pom("2.51.0-rc1", "gax"))
+		result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge", Rules: rules})
+		assert.NoError(t, result.Err)
+		assert.True(t, result.Updated)
+		assert.Equal(t, strings.TrimSpace(pom("2.51.0-rc1", "gax")), runGitT(t, repoDir, "show", "origin/protobuf-4.x-rc:pom.xml"))
+	})
+
+	t.Run("leaves other conflicts", func(t *testing.T) {
+		repoDir := setup(t, pom("2.51.0-rc1", "gax-grpc"))
+		result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge", Rules: rules})
+		assert.Equal(t, []string{"pom.xml"}, result.Conflicts)
+		assert.False(t, result.Updated)
+	})
+}
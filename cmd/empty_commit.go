@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	"os/exec"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
 )
@@ -16,18 +15,23 @@ var emptyCommitCmd = &cobra.Command{
 		all, _ := cmd.Flags().GetBool("all")
 		branch, _ := cmd.Flags().GetString("branch")
 		message, _ := cmd.Flags().GetString("message")
+		resume, _ := cmd.Flags().GetString("resume")
 
 		if repo != "" {
-			emptyCommit(repo, branch, message)
+			emptyCommit(repo, branch, message, nil)
 		} else if all {
-			repos, err := readLines("github_repositories.txt")
+			repos, err := readRepos("github_repositories.txt")
 			if err != nil {
 				fmt.Println("Error reading repositories file:", err)
 				return
 			}
+			journal, err := startRun("empty-commit", resume)
+			if err != nil {
+				fmt.Println("Error starting run:", err)
+				return
+			}
 			for _, r := range repos {
-				repoDir := filepath.Base(r)
-				emptyCommit(repoDir, branch, message)
+				emptyCommit(r.Dir(), r.BranchOr(branch), message, journal)
 			}
 		} else {
 			fmt.Println("Please specify either a single repo with --repo or all repos with --all")
@@ -41,33 +45,44 @@ func init() {
 	emptyCommitCmd.Flags().BoolP("all", "a", false, "Update all repositories")
 	emptyCommitCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "The branch to commit to")
 	emptyCommitCmd.Flags().StringP("message", "m", "chore: empty commit", "The commit message")
+	emptyCommitCmd.Flags().String("resume", "", "Resume an interrupted --all run without committing or pushing again")
 }
 
-func emptyCommit(repoDir, branch, message string) {
+// emptyCommit pushes an empty commit to branch. With a journal, a
+// repository whose commit was made but not pushed in an earlier attempt is
+// only pushed.
+func emptyCommit(repoDir, branch, message string, journal *runJournal) {
 	fmt.Printf("--- Pushing empty commit to '%s' in %s ---\n", branch, repoDir)
-
-	// Fetch
-	fetchCmd := exec.Command("git", "fetch", "origin")
-	fetchCmd.Dir = repoDir
-	if output, err := fetchCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error fetching in %s: %s\n%s", repoDir, err, output)
+	if journal.Done(repoDir, "push") {
+		fmt.Printf("Skipping %s: completed in run %s\n", repoDir, journal.ID)
 		return
 	}
 
-	// Checkout
-	checkoutCmd := exec.Command("git", "checkout", branch)
-	checkoutCmd.Dir = repoDir
-	if output, err := checkoutCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error checking out branch in %s: %s\n%s", repoDir, err, output)
-		return
-	}
+	if !journal.Done(repoDir, "commit") {
+		// Fetch
+		fetchCmd := exec.Command("git", "fetch", "origin")
+		fetchCmd.Dir = repoDir
+		if output, err := fetchCmd.CombinedOutput(); err != nil {
+			fmt.Printf("Error fetching in %s: %s\n%s", repoDir, err, output)
+			return
+		}
 
-	// Empty commit
-	commitCmd := exec.Command("git", "commit", "--allow-empty", "-m", message)
-	commitCmd.Dir = repoDir
-	if output, err := commitCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error committing in %s: %s\n%s", repoDir, err, output)
-		return
+		// Checkout
+		checkoutCmd := exec.Command("git", "checkout", branch)
+		checkoutCmd.Dir = repoDir
+		if output, err := checkoutCmd.CombinedOutput(); err != nil {
+			fmt.Printf("Error checking out branch in %s: %s\n%s", repoDir, err, output)
+			return
+		}
+
+		// Empty commit
+		commitCmd := exec.Command("git", "commit", "--allow-empty", "-m", message)
+		commitCmd.Dir = repoDir
+		if output, err := commitCmd.CombinedOutput(); err != nil {
+			fmt.Printf("Error committing in %s: %s\n%s", repoDir, err, output)
+			return
+		}
+		journal.Complete(repoDir, "commit")
 	}
 
 	// Push
@@ -77,6 +92,7 @@ func emptyCommit(repoDir, branch, message string) {
 		fmt.Printf("Error pushing in %s: %s\n%s", repoDir, err, output)
 		return
 	}
+	journal.Complete(repoDir, "push")
 
 	fmt.Printf("Successfully pushed empty commit to '%s' in %s\n", branch, repoDir)
 }
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var foreachCmd = &cobra.Command{
+	Use:   "foreach [flags] -- <command> [args...]",
+	Short: "Run a command in each repository",
+	Long: `Run a command in each repository.
+
+The command and its arguments are run as given in every repository
+directory, in parallel. To use pipes, variables or other shell syntax, pass
+a script with --shell instead, which is run with sh -c. The command can use
+the REPO_OWNER, REPO_NAME, REPO_DIR and REPO_BRANCH environment variables.
+The output and exit status of every repository are printed in list order
+once all commands finish.`,
+	Example: `  repo-manager foreach -- mvn -q dependency:tree
+  repo-manager foreach --repos java-storage,java-pubsub -- grep -rl protobuf-java --include=pom.xml .
+  repo-manager foreach --shell 'git log -1 --format=%h origin/$REPO_BRANCH'`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if shell, _ := cmd.Flags().GetString("shell"); shell != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		names, _ := cmd.Flags().GetStringSlice("repos")
+		branch, _ := cmd.Flags().GetString("branch")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		logDir, _ := cmd.Flags().GetString("log-dir")
+		shell, _ := cmd.Flags().GetString("shell")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		repos, err = selectRepos(repos, names)
+		if err != nil {
+			fmt.Println("Error selecting repositories:", err)
+			return
+		}
+		if logDir != "" {
+			if err := os.MkdirAll(logDir, 0755); err != nil {
+				fmt.Println("Error creating log directory:", err)
+				return
+			}
+		}
+
+		command := args
+		if shell != "" {
+			command = []string{"sh", "-c", shell}
+		}
+		results := runPool(repos, jobs, func(repo repoEntry) foreachResult {
+			return runForeach(repo, repo.BranchOr(branch), command)
+		})
+
+		var failed []string
+		for _, r := range results {
+			fmt.Printf("--- %s (exit %d) ---\n%s", r.Repo.Dir(), r.ExitCode, r.Output)
+			if r.Output != "" && !strings.HasSuffix(r.Output, "\n") {
+				fmt.Println()
+			}
+			if r.ExitCode != 0 {
+				failed = append(failed, r.Repo.Dir())
+			}
+			if logDir != "" {
+				logPath := filepath.Join(logDir, r.Repo.Dir()+".log")
+				if err := os.WriteFile(logPath, []byte(r.Output), 0644); err != nil {
+					fmt.Printf("Error writing %s: %v\n", logPath, err)
+				}
+			}
+		}
+
+		fmt.Printf("Ran in %d repositories, %d failed", len(results), len(failed))
+		if len(failed) > 0 {
+			fmt.Printf(": %s\n", strings.Join(failed, ", "))
+			os.Exit(1)
+		}
+		fmt.Println()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(foreachCmd)
+	foreachCmd.Flags().StringSlice("repos", nil, "Only run in these repositories (name or owner/name)")
+	foreachCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "Value of REPO_BRANCH for repositories that do not list a branch")
+	foreachCmd.Flags().Int("jobs", 8, "Number of repositories to run in parallel")
+	foreachCmd.Flags().String("log-dir", "", "Directory to save each repository's output to as <repo>.log")
+	foreachCmd.Flags().String("shell", "", "Run this script with sh -c instead of a command and arguments")
+}
+
+// foreachResult is the outcome of running the command in one repository.
+type foreachResult struct {
+	Repo     repoEntry
+	ExitCode int
+	Output   string
+}
+
+// runForeach runs command, a program and its arguments, in the repository
+// directory. Failing to start the command is reported as exit code -1.
+func runForeach(repo repoEntry, branch string, command []string) foreachResult {
+	result := foreachResult{Repo: repo}
+	repoDir, err := filepath.Abs(repo.Dir())
+	if err != nil {
+		result.ExitCode = -1
+		result.Output = err.Error()
+		return result
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = repoDir
+	cmd.Env = append(os.Environ(),
+		"REPO_OWNER="+repo.Owner,
+		"REPO_NAME="+repo.Name,
+		"REPO_DIR="+repoDir,
+		"REPO_BRANCH="+branch,
+	)
+	output, err := cmd.CombinedOutput()
+	result.Output = string(output)
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+			result.Output += err.Error()
+		}
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPool(t *testing.T) {
+	var repos []repoEntry
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		repos = append(repos, repoEntry{Owner: "googleapis", Name: name})
+	}
+	results := runPool(repos, 2, func(repo repoEntry) string {
+		return repo.Name
+	})
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, results)
+}
+
+func TestSelectRepos(t *testing.T) {
+	repos := []repoEntry{
+		{Owner: "googleapis", Name: "java-storage"},
+		{Owner: "googleapis", Name: "java-pubsub"},
+		{Owner: "GoogleCloudPlatform", Name: "cloud-opensource-java"},
+	}
+
+	selected, err := selectRepos(repos, []string{"GoogleCloudPlatform/cloud-opensource-java", "java-storage"})
+	assert.NoError(t, err)
+	assert.Equal(t, []repoEntry{repos[0], repos[2]}, selected)
+
+	selected, err = selectRepos(repos, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, repos, selected)
+
+	_, err = selectRepos(repos, []string{"java-missing"})
+	assert.Error(t, err)
+}
+
+func TestRunForeach(t *testing.T) {
+	workDir, err := os.MkdirTemp("", "work")
+	assert.NoError(t, err)
+	defer os.RemoveAll(workDir)
+	assert.NoError(t, os.Mkdir(filepath.Join(workDir, "java-storage"), 0755))
+	t.Chdir(workDir)
+
+	repo := repoEntry{Owner: "googleapis", Name: "java-storage"}
+	result := runForeach(repo, "protobuf-4.x-rc", []string{"sh", "-c", `echo "$REPO_OWNER $REPO_NAME $REPO_BRANCH $(basename "$REPO_DIR") $(basename "$PWD")"`})
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "googleapis java-storage protobuf-4.x-rc java-storage java-storage\n", result.Output)
+
+	result = runForeach(repo, "protobuf-4.x-rc", []string{"sh", "-c", "echo failing; exit 3"})
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "failing\n", result.Output)
+
+	// Arguments are passed as given, without a shell splitting them.
+	result = runForeach(repo, "protobuf-4.x-rc", []string{"printf", "%s|", "a b", "$REPO_NAME"})
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "a b|$REPO_NAME|", result.Output)
+}
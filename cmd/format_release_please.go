@@ -5,7 +5,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -15,18 +14,16 @@ var formatReleasePleaseCmd = &cobra.Command{
 	Use:   "format-release-please",
 	Short: "Formats .github/release-please.yml files to have 'branch' as the first key.",
 	Run: func(cmd *cobra.Command, args []string) {
-		repos, err := readLines("github_repositories.txt")
+		check, _ := cmd.Flags().GetBool("check")
+
+		repos, err := readRepos("github_repositories.txt")
 		if err != nil {
 			log.Fatalf("Failed to read github_repositories.txt: %v", err)
 		}
 
-		for _, repoStr := range repos {
-			repoParts := strings.Split(repoStr, "/")
-			if len(repoParts) != 2 {
-				log.Printf("Skipping invalid repo format: %s", repoStr)
-				continue
-			}
-			repoName := repoParts[1]
+		var changed []string
+		for _, repo := range repos {
+			repoName := repo.Dir()
 			repoDir, err := filepath.Abs(repoName)
 			if err != nil {
 				log.Printf("Could not get absolute path for %s: %v", repoName, err)
@@ -34,40 +31,48 @@ var formatReleasePleaseCmd = &cobra.Command{
 			}
 
 			fmt.Printf("--- Formatting %s ---\n", repoName)
-			formatReleasePlease(repoDir)
+			if formatReleasePlease(repoDir, check) {
+				changed = append(changed, repoName)
+			}
+		}
+
+		if check {
+			reportCheck(changed)
 		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(formatReleasePleaseCmd)
+	formatReleasePleaseCmd.Flags().Bool("check", false, "Report files that would change and print a diff without writing them")
 }
 
-func formatReleasePlease(repoDir string) {
-	configPath := filepath.Join(repoDir, ".github", "release-please.yml")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		configPath = filepath.Join(repoDir, ".github", "release-please.yaml")
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			fmt.Printf("No release-please config found for %s, skipping.\n", repoDir)
-			return
-		}
+// formatReleasePlease moves the 'branch' key first in every branch entry of
+// the release-please.yml file of repoDir. In check mode the file is not
+// written. It reports whether the file changed or would change.
+func formatReleasePlease(repoDir string, check bool) bool {
+	files := locateReleasePleaseFiles(repoDir)
+	if files.Config == "" {
+		fmt.Printf("No release-please config found for %s, skipping.\n", repoDir)
+		return false
 	}
+	configPath := files.path(files.Config)
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		log.Printf("Failed to read %s: %v", configPath, err)
-		return
+		return false
 	}
 
 	var root yaml.Node
 	err = yaml.Unmarshal(data, &root)
 	if err != nil {
 		log.Printf("Failed to unmarshal YAML from %s: %v", configPath, err)
-		return
+		return false
 	}
 
 	if len(root.Content) == 0 {
-		return // empty file
+		return false // empty file
 	}
 	mainMappingNode := root.Content[0]
 
@@ -107,16 +112,25 @@ func formatReleasePlease(repoDir string) {
 		}
 	}
 
-	marshaledData, err := yaml.Marshal(&root)
+	marshaledData, err := marshalYAML(&root)
 	if err != nil {
 		log.Printf("Failed to marshal YAML for %s: %v", configPath, err)
-		return
+		return false
 	}
 
-	err = os.WriteFile(configPath, marshaledData, 0644)
+	changed, err := writeOrCheck(configPath, marshaledData, check)
 	if err != nil {
 		log.Printf("Failed to write %s: %v", configPath, err)
-		return
+		return false
+	}
+
+	switch {
+	case !changed:
+		fmt.Printf("Release-please config for %s is already formatted\n", repoDir)
+	case check:
+		fmt.Printf("Release-please config for %s would be reformatted\n", repoDir)
+	default:
+		fmt.Printf("Successfully formatted release-please config for %s\n", repoDir)
 	}
-	fmt.Printf("Successfully formatted release-please config for %s\n", repoDir)
+	return changed
 }
@@ -27,7 +27,7 @@ branches:
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		formatReleasePlease(repoDir)
+		formatReleasePlease(repoDir, false)
 
 		data, err := os.ReadFile(configPath)
 		assert.NoError(t, err)
@@ -59,7 +59,7 @@ branches:
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		formatReleasePlease(repoDir)
+		formatReleasePlease(repoDir, false)
 
 		data, err := os.ReadFile(configPath)
 		assert.NoError(t, err)
@@ -81,7 +81,55 @@ branches:
 		err = os.WriteFile(configPath, []byte(config), 0644)
 		assert.NoError(t, err)
 
-		formatReleasePlease(repoDir)
+		formatReleasePlease(repoDir, false)
 		// No assertion, just checking for no panic
 	})
+
+	t.Run("check mode reports changes without writing", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		config := `releaseType: java-yoshi
+branches:
+  - releaseType: java-backport
+    branch: 1.0.x
+`
+		githubDir := filepath.Join(repoDir, ".github")
+		os.Mkdir(githubDir, 0755)
+		configPath := filepath.Join(githubDir, "release-please.yml")
+		err = os.WriteFile(configPath, []byte(config), 0644)
+		assert.NoError(t, err)
+
+		assert.True(t, formatReleasePlease(repoDir, true))
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.Equal(t, config, string(data))
+
+		assert.True(t, formatReleasePlease(repoDir, false))
+		assert.False(t, formatReleasePlease(repoDir, true))
+	})
+
+	t.Run("preserves formatting of unchanged files", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		config := `# Release configuration
+releaseType: java-yoshi
+branches:
+  - branch: 1.0.x
+    releaseType: java-backport
+`
+		githubDir := filepath.Join(repoDir, ".github")
+		os.Mkdir(githubDir, 0755)
+		configPath := filepath.Join(githubDir, "release-please.yml")
+		err = os.WriteFile(configPath, []byte(config), 0644)
+		assert.NoError(t, err)
+
+		assert.False(t, formatReleasePlease(repoDir, false))
+		data, err := os.ReadFile(configPath)
+		assert.NoError(t, err)
+		assert.Equal(t, config, string(data))
+	})
 }
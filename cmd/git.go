@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runGit runs git with args in dir and returns its trimmed output. The
+// output is included in the error if the command fails.
+func runGit(dir string, args ...string) (string, error) {
+	return runCommand(dir, "git", args...)
+}
+
+// isGitRepo reports whether dir is the top level of a git working tree.
+func isGitRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// isDirty reports whether the working tree of dir has uncommitted or
+// untracked changes.
+func isDirty(dir string) (bool, error) {
+	output, err := runGit(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// commitsAhead counts the commits on HEAD that are not on ref.
+func commitsAhead(dir, ref string) (int, error) {
+	output, err := runGit(dir, "rev-list", "--count", ref+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(output)
+}
+
+// remoteURL returns the URL of the named remote.
+func remoteURL(dir, remote string) (string, error) {
+	return runGit(dir, "remote", "get-url", remote)
+}
+
+// sameRepoURL compares two remote URLs, ignoring credentials, a trailing
+// .git suffix and case, so that https://TOKEN@github.com/a/b.git and
+// https://github.com/a/b refer to the same repository.
+func sameRepoURL(a, b string) bool {
+	return normalizeRepoURL(a) == normalizeRepoURL(b)
+}
+
+func normalizeRepoURL(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		u.User = nil
+		s = u.Host + u.Path
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/"), ".git")
+	return strings.ToLower(s)
+}
+
+// aheadBehind counts the commits HEAD has that ref lacks (ahead) and the
+// commits ref has that HEAD lacks (behind).
+func aheadBehind(dir, ref string) (int, int, error) {
+	output, err := runGit(dir, "rev-list", "--left-right", "--count", ref+"...HEAD")
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", output)
+	}
+	behind, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	ahead, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
+
+// fetchBranch fetches branch from origin into refs/remotes/origin/<branch>.
+// An explicit refspec is needed because clone --depth 1 only fetches the
+// cloned branch by default.
+func fetchBranch(dir, branch string) error {
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch)
+	_, err := runGit(dir, "fetch", "origin", refspec)
+	return err
+}
+
+// conflictedFiles lists the paths with unresolved merge conflicts.
+func conflictedFiles(dir string) ([]string, error) {
+	output, err := runGit(dir, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// submodule is an entry of a .gitmodules file.
+type submodule struct {
+	Name   string
+	Path   string
+	URL    string
+	Branch string
+}
+
+// readGitmodules parses the .gitmodules file of the repository in dir and
+// returns its submodules keyed by path. A missing file yields no entries.
+func readGitmodules(dir string) (map[string]submodule, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".gitmodules")); os.IsNotExist(err) {
+		return map[string]submodule{}, nil
+	}
+	output, err := runGit(dir, "config", "--file", ".gitmodules", "--get-regexp", `^submodule\.`)
+	if err != nil {
+		// git config exits with 1 when nothing matches.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return map[string]submodule{}, nil
+		}
+		return nil, err
+	}
+
+	byName := make(map[string]*submodule)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		key = strings.TrimPrefix(key, "submodule.")
+		dot := strings.LastIndex(key, ".")
+		if dot < 0 {
+			continue
+		}
+		name, field := key[:dot], key[dot+1:]
+		sm, ok := byName[name]
+		if !ok {
+			sm = &submodule{Name: name}
+			byName[name] = sm
+		}
+		switch field {
+		case "path":
+			sm.Path = value
+		case "url":
+			sm.URL = value
+		case "branch":
+			sm.Branch = value
+		}
+	}
+
+	submodules := make(map[string]submodule)
+	for _, sm := range byName {
+		submodules[sm.Path] = *sm
+	}
+	return submodules, nil
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	tomlSectionRegexp = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+	tomlVersionRef    = regexp.MustCompile(`version\.ref\s*=\s*"([^"]+)"`)
+	tomlVersion       = regexp.MustCompile(`\bversion\s*=\s*"([^"]+)"`)
+
+	catalogModuleRegexp = regexp.MustCompile(`"([\w.-]+):([\w.-]+)(?::[^"]*)?"`)
+	catalogGroupRegexp  = regexp.MustCompile(`\bgroup\s*=\s*"([^"]+)"`)
+	catalogNameRegexp   = regexp.MustCompile(`\bname\s*=\s*"([^"]+)"`)
+
+	gradleVariableRegexp = regexp.MustCompile(`^\$\{?([\w.]+)\}?$`)
+)
+
+// gradleCoordinateRegexp matches "group:artifact:version" string literals,
+// as used by Gradle dependency declarations and version catalogs.
+func gradleCoordinateRegexp(group string) *regexp.Regexp {
+	return regexp.MustCompile(`["']` + regexp.QuoteMeta(group) + `:([\w.-]+):([^"':@\s]+)`)
+}
+
+// gradleMapRegexp matches the map notation
+// group: 'com.google.protobuf', name: 'protobuf-java', version: '3.25.5'.
+func gradleMapRegexp(group string) *regexp.Regexp {
+	return regexp.MustCompile(`group\s*[:=]\s*["']` + regexp.QuoteMeta(group) +
+		`["']\s*,\s*name\s*[:=]\s*["']([^"']+)["']\s*,\s*version\s*[:=]\s*["']([^"']+)["']`)
+}
+
+// gradleExtPrefixes qualify extra properties, as in
+// ${rootProject.ext.protobufVersion}.
+var gradleExtPrefixes = []string{"rootProject.ext.", "project.ext.", "ext."}
+
+// gradleVariableRef returns the variable referenced by a version such as
+// $protobufVersion or ${rootProject.ext.protobufVersion}. Other qualified
+// names are properties of an object, such as project.version or a version
+// catalog accessor, and are ignored along with the project's own $version.
+func gradleVariableRef(version string) (string, bool) {
+	m := gradleVariableRegexp.FindStringSubmatch(version)
+	if m == nil {
+		return "", false
+	}
+	name := m[1]
+	for _, prefix := range gradleExtPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			break
+		}
+	}
+	if strings.Contains(name, ".") || name == "version" {
+		return "", false
+	}
+	return name, true
+}
+
+// gradleVersions returns the start and end offsets of the version of
+// every matching dependency literal in a Gradle build script or version
+// catalog.
+func gradleVersions(content string, rewrite dependencyRewrite) [][2]int {
+	var versions [][2]int
+	for _, re := range []*regexp.Regexp{gradleCoordinateRegexp(rewrite.GroupID), gradleMapRegexp(rewrite.GroupID)} {
+		for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+			if rewrite.matches(rewrite.GroupID, content[m[2]:m[3]]) {
+				versions = append(versions, [2]int{m[4], m[5]})
+			}
+		}
+	}
+	return versions
+}
+
+// gradlePropertyRefs returns the variables used as the version of matching
+// artifacts in a Gradle build script.
+func gradlePropertyRefs(content string, rewrite dependencyRewrite) []string {
+	var refs []string
+	for _, loc := range gradleVersions(content, rewrite) {
+		if name, ok := gradleVariableRef(content[loc[0]:loc[1]]); ok {
+			refs = append(refs, name)
+		}
+	}
+	return refs
+}
+
+// gradleAssignmentRegexps match the common ways a version variable is
+// defined in Groovy and Kotlin build scripts.
+func gradleAssignmentRegexps(name string) []*regexp.Regexp {
+	n := regexp.QuoteMeta(name)
+	return []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^\s*(?:(?:def|val|var|String)\s+)?(?:(?:rootProject\.|project\.)?ext\.)?` + n + `\s*=\s*["']([^"'$]+)["']`),
+		regexp.MustCompile(`extra\[\s*["']` + n + `["']\s*\]\s*=\s*["']([^"'$]+)["']`),
+		regexp.MustCompile(`\bset\(\s*["']` + n + `["']\s*,\s*["']([^"'$]+)["']`),
+		regexp.MustCompile(`\bval\s+` + n + `\s+by\s+extra\(\s*["']([^"'$]+)["']`),
+	}
+}
+
+// rewriteGradle sets the version of every matching dependency literal and
+// of every variable in properties in a Groovy or Kotlin build script.
+func rewriteGradle(content string, rewrite dependencyRewrite, properties []string) (string, []lineChange) {
+	var edits []textEdit
+	for _, loc := range gradleVersions(content, rewrite) {
+		edits = appendVersionEdit(edits, content, []int{loc[0], loc[1], loc[0], loc[1]}, rewrite.Version)
+	}
+	for _, property := range properties {
+		for _, re := range gradleAssignmentRegexps(property) {
+			for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+				edits = appendVersionEdit(edits, content, m, rewrite.Version)
+			}
+		}
+	}
+	return applyEdits(content, edits)
+}
+
+// rewriteGradleProperties sets every property in properties in a
+// gradle.properties file.
+func rewriteGradleProperties(content string, rewrite dependencyRewrite, properties []string) (string, []lineChange) {
+	var edits []textEdit
+	for _, property := range properties {
+		re := regexp.MustCompile(`(?m)^[ \t]*` + regexp.QuoteMeta(property) + `[ \t]*[=:][ \t]*([^\s#]+)[ \t]*$`)
+		for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+			edits = appendVersionEdit(edits, content, m, rewrite.Version)
+		}
+	}
+	return applyEdits(content, edits)
+}
+
+// appendVersionEdit adds an edit replacing the first submatch of m with
+// version, unless it already has that value or refers to a variable.
+func appendVersionEdit(edits []textEdit, content string, m []int, version string) []textEdit {
+	old := content[m[2]:m[3]]
+	if old == version || strings.HasPrefix(old, "$") {
+		return edits
+	}
+	return append(edits, textEdit{Start: m[2], End: m[3], Text: version})
+}
+
+// catalogLine is a line of a version catalog with its section and offset.
+type catalogLine struct {
+	Section string
+	Offset  int
+	Text    string
+}
+
+func catalogLines(content string) []catalogLine {
+	var lines []catalogLine
+	section := ""
+	offset := 0
+	for _, text := range strings.SplitAfter(content, "\n") {
+		if m := tomlSectionRegexp.FindStringSubmatch(text); m != nil {
+			section = strings.TrimSpace(m[1])
+		}
+		lines = append(lines, catalogLine{Section: section, Offset: offset, Text: text})
+		offset += len(text)
+	}
+	return lines
+}
+
+// isCatalogLibrary reports whether a version catalog line declares a
+// matching library, either as module = "group:name" or as group = "group"
+// with name = "name". Plugin entries such as id = "com.google.protobuf" do
+// not match.
+func isCatalogLibrary(line catalogLine, rewrite dependencyRewrite) bool {
+	if line.Section != "libraries" {
+		return false
+	}
+	if m := catalogModuleRegexp.FindStringSubmatch(line.Text); m != nil {
+		return rewrite.matches(m[1], m[2])
+	}
+	group := catalogGroupRegexp.FindStringSubmatch(line.Text)
+	name := catalogNameRegexp.FindStringSubmatch(line.Text)
+	return group != nil && name != nil && rewrite.matches(group[1], name[1])
+}
+
+// catalogPropertyRefs returns the [versions] keys referenced by matching
+// libraries in a Gradle version catalog.
+func catalogPropertyRefs(content string, rewrite dependencyRewrite) []string {
+	var refs []string
+	for _, line := range catalogLines(content) {
+		if !isCatalogLibrary(line, rewrite) {
+			continue
+		}
+		if m := tomlVersionRef.FindStringSubmatch(line.Text); m != nil {
+			refs = append(refs, m[1])
+		}
+	}
+	return refs
+}
+
+// rewriteCatalog sets the version of matching libraries declared with a
+// literal version and of every [versions] key in properties in a Gradle
+// version catalog such as gradle/libs.versions.toml.
+func rewriteCatalog(content string, rewrite dependencyRewrite, properties []string) (string, []lineChange) {
+	keys := make(map[string]bool)
+	for _, p := range properties {
+		keys[p] = true
+	}
+
+	var edits []textEdit
+	for _, line := range catalogLines(content) {
+		var m []int
+		switch {
+		case line.Section == "versions":
+			key, _, found := strings.Cut(line.Text, "=")
+			if found && keys[strings.Trim(strings.TrimSpace(key), `"`)] {
+				m = regexp.MustCompile(`=\s*"([^"]+)"`).FindStringSubmatchIndex(line.Text)
+			}
+		case isCatalogLibrary(line, rewrite):
+			if c := gradleCoordinateRegexp(rewrite.GroupID).FindStringSubmatchIndex(line.Text); c != nil {
+				m = []int{c[0], c[1], c[4], c[5]}
+			} else if !tomlVersionRef.MatchString(line.Text) {
+				m = tomlVersion.FindStringSubmatchIndex(line.Text)
+			}
+		}
+		if m != nil {
+			m = []int{line.Offset + m[0], line.Offset + m[1], line.Offset + m[2], line.Offset + m[3]}
+			edits = appendVersionEdit(edits, content, m, rewrite.Version)
+		}
+	}
+	return applyEdits(content, edits)
+}
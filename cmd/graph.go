@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Show the dependency graph and release order of the repositories",
+	Long: `Show the dependency graph and release order of the repositories.
+
+Every pom.xml of each cloned repository is parsed for the artifacts it
+produces and the artifacts it uses as parent, dependency or plugin. A
+repository depends on another if it uses one of the other's artifacts. POMs
+under samples/ or src/test/ are ignored, and POMs that cannot be parsed are
+skipped with a warning. If the repositories still depend on each other in a
+cycle, one dependency of the cycle is ignored with a warning, preferring one
+that only comes from an imported BOM. The release order lists every
+repository after the repositories it depends on, keeping the order of
+github_repositories.txt where there is a choice.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		graph, err := buildRepoGraph(repos)
+		if err != nil {
+			fmt.Println("Error building dependency graph:", err)
+			os.Exit(1)
+		}
+		order, err := graph.Order()
+		if err != nil {
+			fmt.Println("Error ordering repositories:", err)
+			os.Exit(1)
+		}
+
+		switch format {
+		case "text":
+			printGraphText(graph, order)
+		case "order":
+			for _, repo := range order {
+				fmt.Println(repo.FullName())
+			}
+		case "dot":
+			fmt.Print(graph.DOT())
+		case "mermaid":
+			fmt.Print(graph.Mermaid())
+		case "json":
+			data, err := json.MarshalIndent(graph.JSON(order), "", "  ")
+			if err != nil {
+				fmt.Println("Error encoding graph:", err)
+				return
+			}
+			fmt.Println(string(data))
+		default:
+			fmt.Printf("Unknown format %q, expected text, order, dot, mermaid or json\n", format)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringP("format", "f", "text", "Output format: text, order, dot, mermaid or json")
+}
+
+// repoGraph is the dependency graph between the managed repositories.
+type repoGraph struct {
+	// Repos are the repositories in list order.
+	Repos []repoEntry
+	// Artifacts maps each repository's full name to the groupId:artifactId
+	// of the artifacts it produces.
+	Artifacts map[string][]string
+	// Deps maps each repository's full name to the repositories it depends
+	// on, in list order.
+	Deps map[string][]string
+	// Versions maps each produced groupId:artifactId to its version.
+	Versions map[string]string
+}
+
+// buildRepoGraph parses the POMs of every cloned repository and relates the
+// repositories through the artifacts they produce and use. Repositories
+// that are not cloned are kept as nodes without artifacts. Samples and test
+// POMs are left out: samples import the libraries-bom, which would make
+// every library depend on the repository that releases it. Any cycle that
+// remains is broken with a warning.
+func buildRepoGraph(repos []repoEntry) (*repoGraph, error) {
+	graph := &repoGraph{
+		Repos:     repos,
+		Artifacts: make(map[string][]string),
+		Deps:      make(map[string][]string),
+		Versions:  make(map[string]string),
+	}
+
+	producer := make(map[string]string)
+	poms := make(map[string][]*pomProject)
+	for _, repo := range repos {
+		if _, err := os.Stat(repo.Dir()); err != nil {
+			continue
+		}
+		projects, err := readRepoPOMs(repo.Dir())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.FullName(), err)
+		}
+		for _, p := range projects {
+			if !isBuildGraphPOM(repo.Dir(), p.Path) {
+				continue
+			}
+			poms[repo.FullName()] = append(poms[repo.FullName()], p)
+			key := p.Key()
+			if other, ok := producer[key]; ok {
+				if other != repo.FullName() {
+					fmt.Fprintf(os.Stderr, "Warning: %s in %s is also produced by %s, ignoring\n", key, p.Path, other)
+				}
+				continue
+			}
+			producer[key] = repo.FullName()
+			graph.Artifacts[repo.FullName()] = append(graph.Artifacts[repo.FullName()], key)
+			graph.Versions[key] = p.resolve(p.EffectiveVersion())
+		}
+	}
+
+	index := make(map[string]int)
+	for i, repo := range repos {
+		index[repo.FullName()] = i
+	}
+	// importOnly holds the edges that come only from BOM imports, which are
+	// dropped first when breaking a cycle.
+	importOnly := make(map[[2]string]bool)
+	for _, repo := range repos {
+		// deps maps each dependency to whether it is used other than as an
+		// imported BOM.
+		deps := make(map[string]bool)
+		for _, p := range poms[repo.FullName()] {
+			for _, ref := range p.References() {
+				if other, ok := producer[ref.Key()]; ok && other != repo.FullName() {
+					deps[other] = deps[other] || ref.Scope != "import"
+				}
+			}
+		}
+		for dep, direct := range deps {
+			if !direct {
+				importOnly[[2]string{repo.FullName(), dep}] = true
+			}
+		}
+		var list []string
+		for dep := range deps {
+			list = append(list, dep)
+		}
+		sort.Slice(list, func(i, j int) bool { return index[list[i]] < index[list[j]] })
+		graph.Deps[repo.FullName()] = list
+	}
+	graph.breakCycles(importOnly)
+	return graph, nil
+}
+
+// breakCycles removes one dependency from every cycle, preferring one that
+// only comes from a BOM import, and warns about each removal.
+func (g *repoGraph) breakCycles(importOnly map[[2]string]bool) {
+	for {
+		cycle := g.findCycle()
+		if cycle == nil {
+			return
+		}
+		// Without an import-only edge, drop the edge that closes the cycle
+		// when walking the repositories in list order.
+		from, to := cycle[len(cycle)-2], cycle[len(cycle)-1]
+		for i := len(cycle) - 2; i >= 0; i-- {
+			if importOnly[[2]string{cycle[i], cycle[i+1]}] {
+				from, to = cycle[i], cycle[i+1]
+				break
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Warning: dependency cycle %s, ignoring the dependency of %s on %s\n", strings.Join(cycle, " -> "), from, to)
+		var kept []string
+		for _, dep := range g.Deps[from] {
+			if dep != to {
+				kept = append(kept, dep)
+			}
+		}
+		g.Deps[from] = kept
+	}
+}
+
+// findCycle returns a dependency cycle as the list of repositories along
+// it, starting and ending with the same repository, or nil if there is
+// none.
+func (g *repoGraph) findCycle() []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(repo string) []string
+	visit = func(repo string) []string {
+		state[repo] = visiting
+		stack = append(stack, repo)
+		for _, dep := range g.Deps[repo] {
+			switch state[dep] {
+			case visiting:
+				for i, r := range stack {
+					if r == dep {
+						return append(append([]string(nil), stack[i:]...), dep)
+					}
+				}
+			case 0:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[repo] = visited
+		return nil
+	}
+	for _, repo := range g.Repos {
+		if state[repo.FullName()] == 0 {
+			if cycle := visit(repo.FullName()); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// isBuildGraphPOM reports whether the POM at path is part of the build of
+// the repository in repoDir rather than a sample or a test fixture.
+func isBuildGraphPOM(repoDir, pomPath string) bool {
+	rel, err := filepath.Rel(repoDir, pomPath)
+	if err != nil {
+		return true
+	}
+	rel = filepath.ToSlash(rel)
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+		if dir == "samples" {
+			return false
+		}
+	}
+	return !strings.Contains("/"+rel, "/src/test/")
+}
+
+// Order returns the repositories so that every repository follows the
+// repositories it depends on. Among repositories whose dependencies are
+// all placed, the one listed first goes first.
+func (g *repoGraph) Order() ([]repoEntry, error) {
+	placed := make(map[string]bool)
+	var order []repoEntry
+	for len(order) < len(g.Repos) {
+		progress := false
+		for _, repo := range g.Repos {
+			if placed[repo.FullName()] || !g.depsPlaced(repo.FullName(), placed) {
+				continue
+			}
+			placed[repo.FullName()] = true
+			order = append(order, repo)
+			progress = true
+			break
+		}
+		if !progress {
+			var cycle []string
+			for _, repo := range g.Repos {
+				if !placed[repo.FullName()] {
+					cycle = append(cycle, repo.FullName())
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+		}
+	}
+	return order, nil
+}
+
+func (g *repoGraph) depsPlaced(repo string, placed map[string]bool) bool {
+	for _, dep := range g.Deps[repo] {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// DOT renders the graph in Graphviz format, with edges pointing from a
+// dependency to the repositories that use it.
+func (g *repoGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph repositories {\n  rankdir=LR;\n")
+	for _, repo := range g.Repos {
+		fmt.Fprintf(&b, "  %q;\n", repo.Name)
+	}
+	for _, repo := range g.Repos {
+		for _, dep := range g.Deps[repo.FullName()] {
+			fmt.Fprintf(&b, "  %q -> %q;\n", repoNameOf(dep), repo.Name)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (g *repoGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, repo := range g.Repos {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", mermaidID(repo.Name), repo.Name)
+	}
+	for _, repo := range g.Repos {
+		for _, dep := range g.Deps[repo.FullName()] {
+			fmt.Fprintf(&b, "  %s --> %s\n", mermaidID(repoNameOf(dep)), mermaidID(repo.Name))
+		}
+	}
+	return b.String()
+}
+
+func mermaidID(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// repoNameOf returns the name part of owner/name.
+func repoNameOf(fullName string) string {
+	return fullName[strings.LastIndex(fullName, "/")+1:]
+}
+
+// graphNode is the JSON form of a repository in the graph.
+type graphNode struct {
+	Repo      string   `json:"repo"`
+	Artifacts []string `json:"artifacts"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// graphJSON is the JSON form of the graph.
+type graphJSON struct {
+	Repos []graphNode `json:"repos"`
+	Order []string    `json:"order"`
+}
+
+// JSON returns the graph and the given release order in JSON form.
+func (g *repoGraph) JSON(order []repoEntry) graphJSON {
+	out := graphJSON{Repos: []graphNode{}, Order: []string{}}
+	for _, repo := range g.Repos {
+		out.Repos = append(out.Repos, graphNode{
+			Repo:      repo.FullName(),
+			Artifacts: append([]string{}, g.Artifacts[repo.FullName()]...),
+			DependsOn: append([]string{}, g.Deps[repo.FullName()]...),
+		})
+	}
+	for _, repo := range order {
+		out.Order = append(out.Order, repo.FullName())
+	}
+	return out
+}
+
+func printGraphText(g *repoGraph, order []repoEntry) {
+	for i, repo := range order {
+		deps := g.Deps[repo.FullName()]
+		if len(deps) == 0 {
+			fmt.Printf("%2d. %s\n", i+1, repo.FullName())
+			continue
+		}
+		fmt.Printf("%2d. %s (depends on %s)\n", i+1, repo.FullName(), strings.Join(deps, ", "))
+	}
+}
+
+// reposInDependencyOrder returns repos ordered so that every repository
+// follows the repositories it depends on.
+func reposInDependencyOrder(repos []repoEntry) ([]repoEntry, error) {
+	graph, err := buildRepoGraph(repos)
+	if err != nil {
+		return nil, err
+	}
+	return graph.Order()
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writePOM writes a POM for groupId:artifactId:version at path with the
+// given extra elements.
+func writePOM(t *testing.T, path, groupID, artifactID, version, extra string) {
+	t.Helper()
+	content := fmt.Sprintf(`<project>
+  <groupId>%s</groupId>
+  <artifactId>%s</artifactId>
+  <version>%s</version>
+%s
+</project>
+`, groupID, artifactID, version, extra)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// setupGraphRepos creates shared-config <- platform <- storage <- bom, listed
+// in reverse order, in a temporary working directory.
+func setupGraphRepos(t *testing.T) []repoEntry {
+	t.Chdir(t.TempDir())
+
+	writePOM(t, "java-shared-config/pom.xml", "com.google.cloud", "google-cloud-shared-config", "1.11.0", "")
+	writePOM(t, "sdk-platform-java/pom.xml", "com.google.api", "gapic-generator-java-pom-parent", "2.45.0", `  <parent>
+    <groupId>com.google.cloud</groupId>
+    <artifactId>google-cloud-shared-config</artifactId>
+    <version>1.11.0</version>
+  </parent>`)
+	writePOM(t, "sdk-platform-java/gax/pom.xml", "com.google.api", "gax", "2.53.0", `  <properties>
+    <protobuf.version>3.25.5</protobuf.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>com.google.protobuf</groupId>
+      <artifactId>protobuf-java</artifactId>
+      <version>${protobuf.version}</version>
+    </dependency>
+  </dependencies>`)
+	writePOM(t, "java-storage/pom.xml", "com.google.cloud", "google-cloud-storage", "2.43.0", `  <dependencies>
+    <dependency>
+      <groupId>com.google.api</groupId>
+      <artifactId>gax</artifactId>
+    </dependency>
+  </dependencies>`)
+	writePOM(t, "java-cloud-bom/pom.xml", "com.google.cloud", "libraries-bom", "26.47.0", `  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.google.cloud</groupId>
+        <artifactId>google-cloud-storage</artifactId>
+        <version>2.43.0</version>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>`)
+
+	return []repoEntry{
+		{Owner: "googleapis", Name: "java-cloud-bom"},
+		{Owner: "googleapis", Name: "java-storage"},
+		{Owner: "googleapis", Name: "java-not-cloned"},
+		{Owner: "googleapis", Name: "sdk-platform-java"},
+		{Owner: "googleapis", Name: "java-shared-config"},
+	}
+}
+
+func TestRepoGraph(t *testing.T) {
+	repos := setupGraphRepos(t)
+
+	graph, err := buildRepoGraph(repos)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"googleapis/java-shared-config"}, graph.Deps["googleapis/sdk-platform-java"])
+	assert.Equal(t, []string{"googleapis/sdk-platform-java"}, graph.Deps["googleapis/java-storage"])
+	assert.Equal(t, []string{"googleapis/java-storage"}, graph.Deps["googleapis/java-cloud-bom"])
+	assert.Equal(t, "2.53.0", graph.Versions["com.google.api:gax"])
+
+	order, err := graph.Order()
+	assert.NoError(t, err)
+	var names []string
+	for _, repo := range order {
+		names = append(names, repo.Name)
+	}
+	assert.Equal(t, []string{"java-not-cloned", "java-shared-config", "sdk-platform-java", "java-storage", "java-cloud-bom"}, names)
+
+	assert.Contains(t, graph.DOT(), `"java-shared-config" -> "sdk-platform-java";`)
+	assert.Contains(t, graph.Mermaid(), "sdk_platform_java --> java_storage")
+	assert.Equal(t, []string{"googleapis/java-storage"}, graph.JSON(order).Repos[0].DependsOn)
+}
+
+func TestRepoGraphCycle(t *testing.T) {
+	repos := setupGraphRepos(t)
+	writePOM(t, "java-shared-config/pom.xml", "com.google.cloud", "google-cloud-shared-config", "1.11.0", `  <dependencies>
+    <dependency>
+      <groupId>com.google.cloud</groupId>
+      <artifactId>libraries-bom</artifactId>
+    </dependency>
+  </dependencies>`)
+
+	graph, err := buildRepoGraph(repos)
+	assert.NoError(t, err)
+	assert.Empty(t, graph.Deps["googleapis/java-shared-config"])
+
+	_, err = reposInDependencyOrder(repos)
+	assert.NoError(t, err)
+}
+
+func TestRepoGraphImports(t *testing.T) {
+	repos := setupGraphRepos(t)
+	writePOM(t, "java-storage/google-cloud-storage-bom/pom.xml", "com.google.cloud", "google-cloud-storage-bom", "2.43.0", "")
+	writePOM(t, "java-cloud-bom/pom.xml", "com.google.cloud", "libraries-bom", "26.47.0", `  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.google.cloud</groupId>
+        <artifactId>google-cloud-storage-bom</artifactId>
+        <version>2.43.0</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>`)
+	// An import that closes a cycle is the dependency that is dropped.
+	writePOM(t, "sdk-platform-java/showcase/pom.xml", "com.google.api", "showcase", "0.0.1", `  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.google.cloud</groupId>
+        <artifactId>libraries-bom</artifactId>
+        <version>26.47.0</version>
+        <type>pom</type>
+        <scope>import</scope>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>`)
+
+	graph, err := buildRepoGraph(repos)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"googleapis/java-storage"}, graph.Deps["googleapis/java-cloud-bom"])
+	assert.Equal(t, []string{"googleapis/java-shared-config"}, graph.Deps["googleapis/sdk-platform-java"])
+}
+
+func TestRepoGraphIgnoresSamples(t *testing.T) {
+	repos := setupGraphRepos(t)
+	writePOM(t, "java-storage/samples/snippets/pom.xml", "com.google.cloud", "storage-snippets", "0.0.1", `  <dependencies>
+    <dependency>
+      <groupId>com.google.cloud</groupId>
+      <artifactId>libraries-bom</artifactId>
+    </dependency>
+  </dependencies>`)
+	writePOM(t, "java-storage/google-cloud-storage/src/test/resources/pom.xml", "com.google.cloud", "google-cloud-storage", "0.0.1", "")
+	assert.NoError(t, os.MkdirAll("sdk-platform-java/template", 0755))
+	assert.NoError(t, os.WriteFile("sdk-platform-java/template/pom.xml", []byte("<project>{{ .Name }"), 0644))
+
+	graph, err := buildRepoGraph(repos)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"googleapis/java-shared-config"}, graph.Deps["googleapis/sdk-platform-java"])
+	assert.Equal(t, []string{"googleapis/sdk-platform-java"}, graph.Deps["googleapis/java-storage"])
+	assert.Equal(t, "2.43.0", graph.Versions["com.google.cloud:google-cloud-storage"])
+	assert.NotContains(t, graph.Versions, "com.google.cloud:storage-snippets")
+
+	_, err = graph.Order()
+	assert.NoError(t, err)
+}
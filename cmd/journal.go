@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// journalDir holds the journals of fleet runs.
+var journalDir = filepath.Join(".repo-manager", "runs")
+
+// stepDone marks a repository whose work in a run is complete.
+const stepDone = "done"
+
+// runJournal records which steps of a fleet run have completed for each
+// repository, so that an interrupted run can be resumed with --resume
+// without repeating side effects such as pushes. The journal is saved after
+// every completed step. A nil journal records nothing.
+type runJournal struct {
+	ID      string              `json:"id"`
+	Command string              `json:"command"`
+	Started time.Time           `json:"started"`
+	Steps   map[string][]string `json:"steps"`
+
+	mu sync.Mutex
+}
+
+// startRun returns the journal for a run of command. With an empty resume
+// a new run is started; otherwise the journal of run resume is loaded.
+func startRun(command, resume string) (*runJournal, error) {
+	if resume == "" {
+		now := time.Now()
+		id, err := claimRunID(fmt.Sprintf("%s-%s", command, now.Format("20060102-150405")))
+		if err != nil {
+			return nil, err
+		}
+		j := &runJournal{
+			ID:      id,
+			Command: command,
+			Started: now,
+			Steps:   make(map[string][]string),
+		}
+		if err := j.save(); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Run ID: %s (continue an interrupted run with --resume %s)\n", j.ID, j.ID)
+		return j, nil
+	}
+
+	data, err := os.ReadFile(journalPath(resume))
+	if err != nil {
+		return nil, fmt.Errorf("unknown run %q: %w", resume, err)
+	}
+	j := &runJournal{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("%s: %w", journalPath(resume), err)
+	}
+	if j.Command != command {
+		return nil, fmt.Errorf("run %s is a %s run, not %s", resume, j.Command, command)
+	}
+	if j.Steps == nil {
+		j.Steps = make(map[string][]string)
+	}
+	fmt.Printf("Resuming run %s\n", j.ID)
+	return j, nil
+}
+
+// claimRunID creates the journal file of a new run exclusively, so that two
+// runs started in the same second get different IDs: base, then base-2,
+// base-3 and so on.
+func claimRunID(base string) (string, error) {
+	if err := os.MkdirAll(journalDir, 0755); err != nil {
+		return "", err
+	}
+	for n := 1; ; n++ {
+		id := base
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		f, err := os.OpenFile(journalPath(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return id, f.Close()
+	}
+}
+
+func journalPath(id string) string {
+	return filepath.Join(journalDir, id+".json")
+}
+
+// Done reports whether step has completed for repo.
+func (j *runJournal) Done(repo, step string) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, s := range j.Steps[repo] {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete records that step has completed for repo and saves the journal.
+// A failure to save is reported but does not fail the step.
+func (j *runJournal) Complete(repo, step string) {
+	if j == nil || j.Done(repo, step) {
+		return
+	}
+	j.mu.Lock()
+	j.Steps[repo] = append(j.Steps[repo], step)
+	j.mu.Unlock()
+	if err := j.save(); err != nil {
+		fmt.Printf("Warning: could not save run journal: %v\n", err)
+	}
+}
+
+// Step runs fn unless step has already completed for repo, and records
+// the step if fn succeeds.
+func (j *runJournal) Step(repo, step string, fn func() error) error {
+	if j.Done(repo, step) {
+		fmt.Printf("Skipping %s for %s: completed in run %s\n", step, repo, j.ID)
+		return nil
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	j.Complete(repo, step)
+	return nil
+}
+
+// save writes the journal atomically, so an interrupted write never loses
+// the steps already recorded.
+func (j *runJournal) save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(journalDir, 0755); err != nil {
+		return err
+	}
+	tmp := journalPath(j.ID) + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, journalPath(j.ID))
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunJournal(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	journal, err := startRun("update-branch", "")
+	assert.NoError(t, err)
+	journal.Complete("java-storage", "update")
+	assert.Error(t, journal.Step("java-storage", "push", func() error { return errors.New("rate limited") }))
+
+	resumed, err := startRun("update-branch", journal.ID)
+	assert.NoError(t, err)
+	assert.True(t, resumed.Done("java-storage", "update"))
+	assert.False(t, resumed.Done("java-storage", "push"))
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, resumed.Step("java-storage", "push", func() error { calls++; return nil }))
+	}
+	assert.Equal(t, 1, calls)
+
+	_, err = startRun("clone", journal.ID)
+	assert.Error(t, err)
+	_, err = startRun("update-branch", "missing")
+	assert.Error(t, err)
+
+	// Runs started in the same second get their own journals.
+	first, err := startRun("clone", "")
+	assert.NoError(t, err)
+	second, err := startRun("clone", "")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	var none *runJournal
+	assert.False(t, none.Done("java-storage", "push"))
+	none.Complete("java-storage", "push")
+}
+
+func TestUpdateBranchResume(t *testing.T) {
+	repoDir := setupClone(t)
+	t.Chdir(t.TempDir())
+	journal, err := startRun("update-branch", "")
+	assert.NoError(t, err)
+
+	// An earlier attempt merged main but failed to push.
+	runGitT(t, repoDir, "checkout", "protobuf-4.x-rc")
+	commitFile(t, repoDir, "merged.txt", "merged")
+	journal.Complete(repoDir, "update")
+
+	result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge", Journal: journal})
+	assert.NoError(t, result.Err)
+	assert.True(t, result.Updated)
+	assert.Equal(t, runGitT(t, repoDir, "rev-parse", "HEAD"), runGitT(t, repoDir, "ls-remote", "origin", "refs/heads/protobuf-4.x-rc")[:40])
+	assert.True(t, journal.Done(repoDir, stepDone))
+
+	result = updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge", Journal: journal})
+	assert.NoError(t, result.Err)
+	assert.False(t, result.Updated)
+}
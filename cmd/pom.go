@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pomDependency is a dependency, managed dependency or plugin in a POM.
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Type       string `xml:"type"`
+	Scope      string `xml:"scope"`
+}
+
+// Key returns groupId:artifactId.
+func (d pomDependency) Key() string {
+	return d.GroupID + ":" + d.ArtifactID
+}
+
+// pomProperties holds the <properties> of a POM by name.
+type pomProperties map[string]string
+
+// UnmarshalXML decodes the arbitrary child elements of <properties>.
+func (p *pomProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = pomProperties{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &t); err != nil {
+				return err
+			}
+			(*p)[t.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// pomProject is the part of a Maven POM used to relate repositories and
+// their versions.
+type pomProject struct {
+	GroupID              string          `xml:"groupId"`
+	ArtifactID           string          `xml:"artifactId"`
+	Version              string          `xml:"version"`
+	Packaging            string          `xml:"packaging"`
+	Parent               *pomDependency  `xml:"parent"`
+	Properties           pomProperties   `xml:"properties"`
+	Dependencies         []pomDependency `xml:"dependencies>dependency"`
+	DependencyManagement []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Plugins              []pomDependency `xml:"build>plugins>plugin"`
+	PluginManagement     []pomDependency `xml:"build>pluginManagement>plugins>plugin"`
+
+	// Path is the file the POM was read from.
+	Path string `xml:"-"`
+}
+
+// readPOM parses the POM at path.
+func readPOM(path string) (*pomProject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePOM(data, path)
+}
+
+// parsePOM parses POM content read from path.
+func parsePOM(data []byte, path string) (*pomProject, error) {
+	var project pomProject
+	if err := xml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	project.Path = path
+	return &project, nil
+}
+
+// readRepoPOMs parses every pom.xml in a repository, skipping build output.
+// POMs that cannot be parsed, such as templates, are reported on stderr and
+// skipped.
+func readRepoPOMs(repoDir string) ([]*pomProject, error) {
+	paths, err := findFiles(repoDir, func(name string) bool { return name == "pom.xml" })
+	if err != nil {
+		return nil, err
+	}
+	var poms []*pomProject
+	for _, path := range paths {
+		pom, err := readPOM(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %v\n", err)
+			continue
+		}
+		poms = append(poms, pom)
+	}
+	return poms, nil
+}
+
+// readPOMsAtRef parses every pom.xml in a repository as of ref, such as a
+// release tag, without checking it out. Like buildRepoGraph, it leaves out
+// samples and test POMs, and skips POMs that cannot be parsed with a
+// warning.
+func readPOMsAtRef(repoDir, ref string) ([]*pomProject, error) {
+	output, err := runGit(repoDir, "ls-tree", "-r", "--name-only", ref)
+	if err != nil {
+		return nil, err
+	}
+	var poms []*pomProject
+	for _, path := range strings.Split(output, "\n") {
+		if filepath.Base(path) != "pom.xml" || inSkippedDir(path) || !isBuildGraphPOM(repoDir, filepath.Join(repoDir, path)) {
+			continue
+		}
+		content, err := runGit(repoDir, "show", ref+":"+path)
+		if err != nil {
+			return nil, err
+		}
+		pom, err := parsePOM([]byte(content), path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %v (at %s)\n", err, ref)
+			continue
+		}
+		poms = append(poms, pom)
+	}
+	return poms, nil
+}
+
+// EffectiveGroupID returns the project's groupId, inherited from its parent
+// if not set.
+func (p *pomProject) EffectiveGroupID() string {
+	if p.GroupID == "" && p.Parent != nil {
+		return p.Parent.GroupID
+	}
+	return p.GroupID
+}
+
+// EffectiveVersion returns the project's version, inherited from its parent
+// if not set.
+func (p *pomProject) EffectiveVersion() string {
+	if p.Version == "" && p.Parent != nil {
+		return p.Parent.Version
+	}
+	return p.Version
+}
+
+// Key returns the project's groupId:artifactId.
+func (p *pomProject) Key() string {
+	return p.EffectiveGroupID() + ":" + p.ArtifactID
+}
+
+// References returns every artifact the project refers to: its parent,
+// dependencies, managed dependencies and plugins, with groupIds resolved.
+func (p *pomProject) References() []pomDependency {
+	var refs []pomDependency
+	if p.Parent != nil {
+		refs = append(refs, *p.Parent)
+	}
+	for _, list := range [][]pomDependency{p.Dependencies, p.DependencyManagement, p.Plugins, p.PluginManagement} {
+		for _, d := range list {
+			d.GroupID = p.resolve(d.GroupID)
+			if d.GroupID == "" {
+				// Plugins default to org.apache.maven.plugins.
+				d.GroupID = "org.apache.maven.plugins"
+			}
+			refs = append(refs, d)
+		}
+	}
+	return refs
+}
+
+var pomPropertyRegexp = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// resolve substitutes ${...} references to the project's own properties
+// and coordinates. Properties defined elsewhere, such as in a parent POM,
+// are left unresolved.
+func (p *pomProject) resolve(value string) string {
+	for i := 0; i < 10 && strings.Contains(value, "${"); i++ {
+		next := pomPropertyRegexp.ReplaceAllStringFunc(value, func(ref string) string {
+			name := ref[2 : len(ref)-1]
+			switch name {
+			case "project.groupId", "pom.groupId":
+				return p.EffectiveGroupID()
+			case "project.version", "pom.version":
+				return p.EffectiveVersion()
+			case "project.artifactId":
+				return p.ArtifactID
+			case "project.parent.version":
+				if p.Parent != nil {
+					return p.Parent.Version
+				}
+			}
+			if v, ok := p.Properties[name]; ok {
+				return v
+			}
+			return ref
+		})
+		if next == value {
+			break
+		}
+		value = next
+	}
+	return value
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// lineChange is a single version rewrite within a file.
+type lineChange struct {
+	Line int
+	Old  string
+	New  string
+}
+
+// dependencyRewrite describes which dependency versions to rewrite.
+type dependencyRewrite struct {
+	// GroupID selects the artifacts to update, e.g. com.google.protobuf.
+	GroupID string
+	// ArtifactID restricts the update to a single artifact of the group.
+	ArtifactID string
+	// Version is the new version.
+	Version string
+	// Properties are version properties to update in addition to those
+	// referenced by matching artifacts, e.g. protobuf.version.
+	Properties []string
+}
+
+var (
+	dependencyBlockRegexp = regexp.MustCompile(`(?s)<dependency>.*?</dependency>`)
+	parentBlockRegexp     = regexp.MustCompile(`(?s)<parent>.*?</parent>`)
+	exclusionsRegexp      = regexp.MustCompile(`(?s)<exclusions>.*?</exclusions>`)
+	groupIDRegexp         = regexp.MustCompile(`<groupId>\s*([^<]+?)\s*</groupId>`)
+	artifactIDRegexp      = regexp.MustCompile(`<artifactId>\s*([^<]+?)\s*</artifactId>`)
+	versionTagRegexp      = regexp.MustCompile(`<version>\s*([^<]+?)\s*</version>`)
+	propertyRefRegexp     = regexp.MustCompile(`^\$\{([^}]+)\}$`)
+)
+
+// matches reports whether groupID:artifactID is selected by the rewrite.
+func (r dependencyRewrite) matches(groupID, artifactID string) bool {
+	return groupID == r.GroupID && (r.ArtifactID == "" || artifactID == r.ArtifactID)
+}
+
+// skippedDirs are never searched for build files.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"target":       true,
+	"build":        true,
+	"node_modules": true,
+	".gradle":      true,
+}
+
+// inSkippedDir reports whether a slash-separated relative path lies in one
+// of the skipped directories.
+func inSkippedDir(path string) bool {
+	parts := strings.Split(path, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		if skippedDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// findFiles returns the files under root whose base name satisfies match,
+// skipping version control and build output directories.
+func findFiles(root string, match func(name string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if match(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// lineAt returns the 1-based line number of offset in content.
+func lineAt(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+// textEdit replaces content[Start:End] with Text.
+type textEdit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// applyEdits applies non-overlapping edits and records a lineChange for each.
+func applyEdits(content string, edits []textEdit) (string, []lineChange) {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+	var b strings.Builder
+	var changes []lineChange
+	last := 0
+	for _, e := range edits {
+		b.WriteString(content[last:e.Start])
+		b.WriteString(e.Text)
+		changes = append(changes, lineChange{Line: lineAt(content, e.Start), Old: content[e.Start:e.End], New: e.Text})
+		last = e.End
+	}
+	b.WriteString(content[last:])
+	return b.String(), changes
+}
+
+// pomPropertyRefs returns the properties used as the version of matching
+// artifacts in a POM, such as protobuf.version in
+// <version>${protobuf.version}</version>. Properties that Maven provides
+// itself, such as ${project.version}, are not returned.
+func pomPropertyRefs(content string, rewrite dependencyRewrite) []string {
+	var refs []string
+	for _, loc := range pomVersions(content, rewrite) {
+		if m := propertyRefRegexp.FindStringSubmatch(content[loc[0]:loc[1]]); m != nil && !isBuiltinPOMProperty(m[1]) {
+			refs = append(refs, m[1])
+		}
+	}
+	return refs
+}
+
+// builtinPOMPropertyPrefixes are the prefixes of properties that Maven
+// derives from the model or the environment.
+var builtinPOMPropertyPrefixes = []string{"project.", "pom.", "parent.", "env.", "settings."}
+
+// isBuiltinPOMProperty reports whether name is provided by Maven rather
+// than defined in <properties>.
+func isBuiltinPOMProperty(name string) bool {
+	if name == "version" {
+		return true
+	}
+	for _, prefix := range builtinPOMPropertyPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// pomVersions returns the start and end offsets of the <version> text of
+// every matching <dependency> and <parent> in a POM.
+func pomVersions(content string, rewrite dependencyRewrite) [][2]int {
+	var versions [][2]int
+	blocks := dependencyBlockRegexp.FindAllStringIndex(content, -1)
+	blocks = append(blocks, parentBlockRegexp.FindAllStringIndex(content, -1)...)
+	for _, loc := range blocks {
+		block := blankExclusions(content[loc[0]:loc[1]])
+		group := groupIDRegexp.FindStringSubmatch(block)
+		artifact := artifactIDRegexp.FindStringSubmatch(block)
+		version := versionTagRegexp.FindStringSubmatchIndex(block)
+		if group == nil || artifact == nil || version == nil || !rewrite.matches(group[1], artifact[1]) {
+			continue
+		}
+		versions = append(versions, [2]int{loc[0] + version[2], loc[0] + version[3]})
+	}
+	return versions
+}
+
+// blankExclusions replaces <exclusions> sections with spaces so that their
+// groupIds are not mistaken for the dependency's own while keeping offsets.
+func blankExclusions(block string) string {
+	return exclusionsRegexp.ReplaceAllStringFunc(block, func(s string) string {
+		return strings.Repeat(" ", len(s))
+	})
+}
+
+// rewritePOM sets the version of every matching dependency or parent with a
+// literal version, every property in properties, and any groupId:protoc:version
+// coordinates, editing only the version text so formatting is preserved.
+func rewritePOM(content string, rewrite dependencyRewrite, properties []string) (string, []lineChange) {
+	var edits []textEdit
+
+	for _, loc := range pomVersions(content, rewrite) {
+		old := content[loc[0]:loc[1]]
+		if propertyRefRegexp.MatchString(old) || old == rewrite.Version {
+			continue
+		}
+		edits = append(edits, textEdit{Start: loc[0], End: loc[1], Text: rewrite.Version})
+	}
+
+	for _, property := range properties {
+		re := regexp.MustCompile(`<` + regexp.QuoteMeta(property) + `>\s*([^<$]+?)\s*</` + regexp.QuoteMeta(property) + `>`)
+		for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+			if content[m[2]:m[3]] != rewrite.Version {
+				edits = append(edits, textEdit{Start: m[2], End: m[3], Text: rewrite.Version})
+			}
+		}
+	}
+
+	// Plugin configuration such as
+	// <protocArtifact>com.google.protobuf:protoc:3.25.5:exe:...</protocArtifact>
+	if rewrite.matches(rewrite.GroupID, "protoc") {
+		coords := regexp.MustCompile(regexp.QuoteMeta(rewrite.GroupID) + `:protoc:([^:<$\s]+)`)
+		for _, m := range coords.FindAllStringSubmatchIndex(content, -1) {
+			if content[m[2]:m[3]] != rewrite.Version {
+				edits = append(edits, textEdit{Start: m[2], End: m[3], Text: rewrite.Version})
+			}
+		}
+	}
+
+	return applyEdits(content, edits)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"sync"
+)
+
+// runPool calls fn for every repository using at most workers goroutines
+// and returns the results in the order of repos.
+func runPool[T any](repos []repoEntry, workers int, fn func(repoEntry) T) []T {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]T, len(repos))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(repos); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = fn(repos[i])
+			}
+		}()
+	}
+	for i := range repos {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
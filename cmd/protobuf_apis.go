@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// protobufAPI is a protobuf Java API that was removed or changed in a way
+// that breaks callers in protobuf 4.x. Pattern is a regular expression
+// matched against each line of the Java sources.
+type protobufAPI struct {
+	Name        string `yaml:"name"`
+	Pattern     string `yaml:"pattern"`
+	Description string `yaml:"description"`
+
+	re *regexp.Regexp
+}
+
+// defaultProtobufAPIs are the breaking changes between protobuf 3.x and
+// 4.x most often seen in the managed libraries.
+var defaultProtobufAPIs = []protobufAPI{
+	{
+		Name:        "GeneratedMessageV3",
+		Pattern:     `\bGeneratedMessageV3\b`,
+		Description: "4.x gencode extends GeneratedMessage; direct use of GeneratedMessageV3 is deprecated",
+	},
+	{
+		Name:        "FieldBuilderV3",
+		Pattern:     `\b(Single|Repeated)FieldBuilderV3\b`,
+		Description: "renamed to SingleFieldBuilder and RepeatedFieldBuilder",
+	},
+	{
+		Name:        "PARSER",
+		Pattern:     `\b[A-Z]\w*\.PARSER\b`,
+		Description: "public PARSER fields are deprecated, use parser()",
+	},
+	{
+		Name:        "TextFormat.print",
+		Pattern:     `\bTextFormat\.(print|printToString|printUnicode|printUnicodeToString|shortDebugString|printField|printFieldToString)\(`,
+		Description: "removed, use TextFormat.printer()",
+	},
+	{
+		Name:        "getSyntax",
+		Pattern:     `\.getSyntax\(\)`,
+		Description: "FileDescriptor.getSyntax() and Syntax were removed, use editions features",
+	},
+	{
+		Name:        "LazyStringList",
+		Pattern:     `\b(UnmodifiableLazyStringList|LazyStringArrayList\.EMPTY)\b`,
+		Description: "removed, use LazyStringArrayList.emptyList()",
+	},
+	{
+		Name:        "makeExtensionsImmutable",
+		Pattern:     `\bmakeExtensionsImmutable\(`,
+		Description: "removed from GeneratedMessage",
+	},
+}
+
+// protobufAPIsFile is the file APIs are read from, in the format
+//
+//	apis:
+//	  - name: PARSER
+//	    pattern: '\b[A-Z]\w*\.PARSER\b'
+//	    description: use parser()
+type protobufAPIsFile struct {
+	APIs []protobufAPI `yaml:"apis"`
+}
+
+// loadProtobufAPIs reads APIs from filename. Without a file name,
+// protobuf-apis.yaml is used if it exists and the built-in list otherwise.
+// The patterns are compiled.
+func loadProtobufAPIs(filename string) ([]protobufAPI, error) {
+	apis := defaultProtobufAPIs
+	if filename == "" {
+		if _, err := os.Stat("protobuf-apis.yaml"); err == nil {
+			filename = "protobuf-apis.yaml"
+		}
+	}
+	if filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		var file protobufAPIsFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+		apis = file.APIs
+	}
+
+	compiled := make([]protobufAPI, len(apis))
+	for i, api := range apis {
+		re, err := regexp.Compile(api.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("api %s: %w", api.Name, err)
+		}
+		api.re = re
+		compiled[i] = api
+	}
+	return compiled, nil
+}
@@ -0,0 +1,7 @@
+package cmd
+
+// openPullRequest opens a pull request from head into base with the gh CLI
+// and returns its URL. head must already be pushed to origin.
+func openPullRequest(repoDir, base, head, title, body string) (string, error) {
+	return runCommand(repoDir, "gh", "pr", "create", "--base", base, "--head", head, "--title", title, "--body", body)
+}
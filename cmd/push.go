@@ -2,8 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"os/exec"
-	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -12,59 +11,195 @@ import (
 var pushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Commit and push changes for each repository",
+	Long: `Commit and push changes for each repository.
+
+By default the release-please files are committed. Use --paths to commit files
+matching globs instead, or --all-tracked to commit every change to tracked
+files. With --pr the commit is pushed to a new branch and a pull request is
+opened against --branch with the gh CLI.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		message, _ := cmd.Flags().GetString("message")
-		pushChanges(message)
+		var opts pushOptions
+		opts.Message, _ = cmd.Flags().GetString("message")
+		opts.Paths, _ = cmd.Flags().GetStringSlice("paths")
+		opts.AllTracked, _ = cmd.Flags().GetBool("all-tracked")
+		opts.Branch, _ = cmd.Flags().GetString("branch")
+		opts.Trailers, _ = cmd.Flags().GetStringArray("trailer")
+		opts.Sign, _ = cmd.Flags().GetBool("sign")
+		opts.SignKey, _ = cmd.Flags().GetString("sign-key")
+		opts.Signoff, _ = cmd.Flags().GetBool("signoff")
+		opts.PR, _ = cmd.Flags().GetBool("pr")
+		opts.PRBranch, _ = cmd.Flags().GetString("pr-branch")
+
+		if opts.AllTracked && len(opts.Paths) > 0 {
+			fmt.Println("Please specify either --paths or --all-tracked, not both")
+			return
+		}
+		pushChanges(opts)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(pushCmd)
 	pushCmd.Flags().StringP("message", "m", "feat: update release-please config", "Commit message")
+	pushCmd.Flags().StringSlice("paths", nil, "Globs of files to commit, e.g. '**/pom.xml' (default: the release-please files)")
+	pushCmd.Flags().Bool("all-tracked", false, "Commit all changes to tracked files")
+	pushCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "Branch to push to, or the pull request base with --pr")
+	pushCmd.Flags().StringArray("trailer", nil, "Commit trailer such as 'Fixes: #123' (repeatable)")
+	pushCmd.Flags().BoolP("sign", "S", false, "GPG-sign the commit")
+	pushCmd.Flags().String("sign-key", "", "Key to sign the commit with (implies --sign)")
+	pushCmd.Flags().BoolP("signoff", "s", false, "Add a Signed-off-by trailer")
+	pushCmd.Flags().Bool("pr", false, "Push to a new branch and open a pull request instead of pushing to --branch")
+	pushCmd.Flags().String("pr-branch", "", "Branch for --pr (default: derived from the commit message)")
+}
+
+// pushOptions controls what pushChange commits and where it goes.
+type pushOptions struct {
+	Message string
+	// Paths are globs of files to commit. When empty and AllTracked is not
+	// set, the release-please files are committed.
+	Paths      []string
+	AllTracked bool
+	Branch     string
+	Trailers   []string
+	Sign       bool
+	SignKey    string
+	Signoff    bool
+	PR         bool
+	PRBranch   string
 }
 
-func pushChanges(message string) {
-	repos, err := readLines("github_repositories.txt")
+func pushChanges(opts pushOptions) {
+	repos, err := readRepos("github_repositories.txt")
 	if err != nil {
 		fmt.Println("Error reading repositories file:", err)
 		return
 	}
 
 	for _, repo := range repos {
-		repoDir := filepath.Base(repo)
-		pushChange(repoDir, message)
+		repoOpts := opts
+		repoOpts.Branch = repo.BranchOr(opts.Branch)
+		pushChange(repo.Dir(), repoOpts)
+	}
+}
+
+var nonBranchChars = regexp.MustCompile(`[^a-z0-9.]+`)
+
+// prBranchFromMessage derives a branch name from the subject of a commit
+// message, e.g. "feat: update config" becomes "repo-manager/feat-update-config".
+func prBranchFromMessage(message string) string {
+	subject := strings.SplitN(message, "\n", 2)[0]
+	slug := strings.Trim(nonBranchChars.ReplaceAllString(strings.ToLower(subject), "-"), "-")
+	return "repo-manager/" + slug
+}
+
+// addArgs returns the git add arguments selecting the files to commit.
+func (opts pushOptions) addArgs(repoDir string) ([]string, error) {
+	if opts.AllTracked {
+		return []string{"add", "--update"}, nil
+	}
+	if len(opts.Paths) > 0 {
+		args := []string{"add", "--"}
+		for _, p := range opts.Paths {
+			args = append(args, ":(glob)"+p)
+		}
+		return args, nil
 	}
+	files := locateReleasePleaseFiles(repoDir).all()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no release-please files found")
+	}
+	return append([]string{"add", "--"}, files...), nil
 }
 
-func pushChange(repoDir, message string) {
+// commitArgs returns the git commit arguments for the message, trailers and
+// signing options.
+func (opts pushOptions) commitArgs() []string {
+	args := []string{"commit", "-m", opts.Message}
+	for _, trailer := range opts.Trailers {
+		args = append(args, "--trailer", trailer)
+	}
+	if opts.SignKey != "" {
+		args = append(args, "--gpg-sign="+opts.SignKey)
+	} else if opts.Sign {
+		args = append(args, "--gpg-sign")
+	}
+	if opts.Signoff {
+		args = append(args, "--signoff")
+	}
+	return args
+}
+
+func pushChange(repoDir string, opts pushOptions) {
 	fmt.Printf("--- Pushing changes for %s ---\n", repoDir)
 
+	// The commit is made on top of the checked out branch, so it has to be
+	// the branch that is pushed to or that the pull request targets.
+	originalBranch, _ := runGit(repoDir, "symbolic-ref", "--short", "-q", "HEAD")
+	if originalBranch != opts.Branch {
+		fmt.Printf("Skipping %s: on branch '%s', expected '%s' (run check-branch --fix)\n", repoDir, originalBranch, opts.Branch)
+		return
+	}
+
 	// Add
-	addCmd := exec.Command("git", "add", "release-please-config.json")
-	addCmd.Dir = repoDir
-	if output, err := addCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error adding changes in %s: %s\n%s", repoDir, err, output)
+	addArgs, err := opts.addArgs(repoDir)
+	if err != nil {
+		fmt.Printf("Error adding changes in %s: %v\n", repoDir, err)
+		return
+	}
+	if _, err := runGit(repoDir, addArgs...); err != nil {
+		fmt.Printf("Error adding changes in %s: %v\n", repoDir, err)
+		return
+	}
+	if _, err := runGit(repoDir, "diff", "--cached", "--quiet"); err == nil {
+		fmt.Printf("No changes to commit in %s\n", repoDir)
 		return
 	}
 
-	// Commit
-	commitCmd := exec.Command("git", "commit", "-m", message)
-	commitCmd.Dir = repoDir
-	if output, err := commitCmd.CombinedOutput(); err != nil {
-		// It's possible there are no changes to commit, so we check for that
-		if !strings.Contains(string(output), "nothing to commit") {
-			fmt.Printf("Error committing in %s: %s\n%s", repoDir, err, output)
+	prBranch := opts.PRBranch
+	if opts.PR {
+		if prBranch == "" {
+			prBranch = prBranchFromMessage(opts.Message)
+		}
+		if _, err := runGit(repoDir, "checkout", "-B", prBranch); err != nil {
+			fmt.Printf("Error creating branch %s in %s: %v\n", prBranch, repoDir, err)
 			return
 		}
-		fmt.Printf("No changes to commit in %s\n", repoDir)
+	}
+
+	// restore switches back from the pull request branch.
+	restore := func() {
+		if opts.PR {
+			runGit(repoDir, "checkout", originalBranch)
+		}
+	}
+
+	// Commit
+	if _, err := runGit(repoDir, opts.commitArgs()...); err != nil {
+		fmt.Printf("Error committing in %s: %v\n", repoDir, err)
+		restore()
 		return
 	}
 
 	// Push
-	pushCmd := exec.Command("git", "push", "origin", "protobuf-4.x-rc")
-	pushCmd.Dir = repoDir
-	if output, err := pushCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error pushing in %s: %s\n%s", repoDir, err, output)
+	target := opts.Branch
+	if opts.PR {
+		target = prBranch
+	}
+	if _, err := runGit(repoDir, "push", "origin", "HEAD:refs/heads/"+target); err != nil {
+		fmt.Printf("Error pushing in %s: %v\n", repoDir, err)
+		restore()
+		return
+	}
+
+	if opts.PR {
+		title := strings.SplitN(opts.Message, "\n", 2)[0]
+		url, err := openPullRequest(repoDir, opts.Branch, prBranch, title, opts.Message)
+		restore()
+		if err != nil {
+			fmt.Printf("Error opening pull request in %s: %v\n", repoDir, err)
+			return
+		}
+		fmt.Printf("Opened pull request for %s: %s\n", repoDir, url)
 		return
 	}
 
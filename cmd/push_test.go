@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushChange(t *testing.T) {
+	t.Run("commits files matching globs", func(t *testing.T) {
+		repoDir := setupClone(t)
+		runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+		assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, "module"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "module", "pom.xml"), []byte("<project/>"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "other.txt"), []byte("not committed"), 0644))
+
+		pushChange(repoDir, pushOptions{
+			Message:  "chore: update poms",
+			Paths:    []string{"**/pom.xml"},
+			Branch:   "protobuf-4.x-rc",
+			Trailers: []string{"Fixes: #123"},
+		})
+
+		assert.Equal(t, "<project/>", runGitT(t, repoDir, "show", "origin/protobuf-4.x-rc:module/pom.xml"))
+		message := runGitT(t, repoDir, "log", "-1", "--format=%B", "origin/protobuf-4.x-rc")
+		assert.Contains(t, message, "chore: update poms")
+		assert.Contains(t, message, "Fixes: #123")
+		assert.Equal(t, "?? other.txt", runGitT(t, repoDir, "status", "--porcelain"))
+	})
+
+	t.Run("commits all tracked changes", func(t *testing.T) {
+		repoDir := setupClone(t)
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "test.txt"), []byte("changed"), 0644))
+
+		pushChange(repoDir, pushOptions{Message: "chore: update", AllTracked: true, Branch: "main", Signoff: true})
+
+		assert.Equal(t, "changed", runGitT(t, repoDir, "show", "origin/main:test.txt"))
+		assert.Contains(t, runGitT(t, repoDir, "log", "-1", "--format=%B", "origin/main"), "Signed-off-by: Test User")
+	})
+
+	t.Run("refuses to push another branch", func(t *testing.T) {
+		repoDir := setupClone(t)
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "test.txt"), []byte("changed"), 0644))
+		remote := runGitT(t, repoDir, "rev-parse", "origin/protobuf-4.x-rc")
+
+		pushChange(repoDir, pushOptions{Message: "chore: update", AllTracked: true, Branch: "protobuf-4.x-rc"})
+
+		assert.Equal(t, remote, runGitT(t, repoDir, "rev-parse", "origin/protobuf-4.x-rc"))
+		assert.Equal(t, "M test.txt", runGitT(t, repoDir, "status", "--porcelain"))
+	})
+
+	t.Run("returns to the branch when the pull request push fails", func(t *testing.T) {
+		repoDir := setupClone(t)
+		runGitT(t, repoDir, "remote", "set-url", "origin", filepath.Join(repoDir, "missing"))
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "test.txt"), []byte("changed"), 0644))
+
+		pushChange(repoDir, pushOptions{Message: "chore: update", AllTracked: true, Branch: "main", PR: true})
+
+		assert.Equal(t, "main", runGitT(t, repoDir, "symbolic-ref", "--short", "HEAD"))
+	})
+
+	t.Run("skips repositories without changes", func(t *testing.T) {
+		repoDir := setupClone(t)
+		head := runGitT(t, repoDir, "rev-parse", "HEAD")
+
+		pushChange(repoDir, pushOptions{Message: "chore: update", AllTracked: true, Branch: "main"})
+
+		assert.Equal(t, head, runGitT(t, repoDir, "rev-parse", "HEAD"))
+	})
+}
+
+func TestPrBranchFromMessage(t *testing.T) {
+	assert.Equal(t, "repo-manager/feat-update-release-please-config", prBranchFromMessage("feat: update release-please config"))
+	assert.Equal(t, "repo-manager/chore-bump-protobuf-to-4.29.0", prBranchFromMessage("chore: bump protobuf to 4.29.0\n\nbody"))
+}
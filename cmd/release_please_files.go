@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// Candidate locations of each release-please related file, relative to the
+// repository root, in order of preference.
+var (
+	releasePleaseConfigPaths = []string{
+		filepath.Join(".github", "release-please.yml"),
+		filepath.Join(".github", "release-please.yaml"),
+	}
+	releasePleaseManifestConfigPaths = []string{
+		"release-please-config.json",
+		filepath.Join(".github", "release-please-config.json"),
+	}
+	releasePleaseManifestPaths = []string{
+		".release-please-manifest.json",
+		filepath.Join(".github", ".release-please-manifest.json"),
+	}
+	syncRepoSettingsPaths = []string{
+		filepath.Join(".github", "sync-repo-settings.yaml"),
+		filepath.Join(".github", "sync-repo-settings.yml"),
+	}
+)
+
+// releasePleaseFiles holds the release-please related files found in a
+// repository. Paths are relative to the repository root and empty when the
+// file does not exist.
+type releasePleaseFiles struct {
+	repoDir string
+	// Config is the release-please GitHub app config, release-please.yml.
+	Config string
+	// ManifestConfig is release-please-config.json.
+	ManifestConfig string
+	// Manifest is .release-please-manifest.json.
+	Manifest string
+	// SyncRepoSettings is the branch protection config, sync-repo-settings.yaml.
+	SyncRepoSettings string
+}
+
+// locateReleasePleaseFiles finds the release-please related files of repoDir.
+func locateReleasePleaseFiles(repoDir string) releasePleaseFiles {
+	return releasePleaseFiles{
+		repoDir:          repoDir,
+		Config:           firstExisting(repoDir, releasePleaseConfigPaths),
+		ManifestConfig:   firstExisting(repoDir, releasePleaseManifestConfigPaths),
+		Manifest:         firstExisting(repoDir, releasePleaseManifestPaths),
+		SyncRepoSettings: firstExisting(repoDir, syncRepoSettingsPaths),
+	}
+}
+
+func firstExisting(repoDir string, candidates []string) string {
+	for _, candidate := range candidates {
+		if info, err := os.Stat(filepath.Join(repoDir, candidate)); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// path joins a relative path returned by the locator with the repository
+// directory.
+func (f releasePleaseFiles) path(rel string) string {
+	return filepath.Join(f.repoDir, rel)
+}
+
+// all returns every file that was found, relative to the repository root.
+func (f releasePleaseFiles) all() []string {
+	var paths []string
+	for _, p := range []string{f.Config, f.ManifestConfig, f.Manifest, f.SyncRepoSettings} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+var releasePleaseFilesCmd = &cobra.Command{
+	Use:   "release-please-files [repo-dir...]",
+	Short: "List the release-please related files of each repository",
+	Long: `List the release-please related files of each repository, one path per line.
+
+Paths are printed relative to the repository when a single directory is given,
+so the output can be passed straight to git add. Without arguments every
+repository in github_repositories.txt is listed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			for _, p := range locateReleasePleaseFiles(args[0]).all() {
+				fmt.Println(p)
+			}
+			return
+		}
+
+		repoDirs := args
+		if len(repoDirs) == 0 {
+			repos, err := readRepos("github_repositories.txt")
+			if err != nil {
+				fmt.Println("Error reading repositories file:", err)
+				return
+			}
+			for _, repo := range repos {
+				repoDirs = append(repoDirs, repo.Dir())
+			}
+		}
+		for _, repoDir := range repoDirs {
+			for _, p := range locateReleasePleaseFiles(repoDir).all() {
+				fmt.Println(filepath.Join(repoDir, p))
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(releasePleaseFilesCmd)
+}
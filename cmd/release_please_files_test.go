@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocateReleasePleaseFiles(t *testing.T) {
+	t.Run("finds yaml extension and manifest files", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		os.Mkdir(filepath.Join(repoDir, ".github"), 0755)
+		for _, name := range []string{
+			".github/release-please.yaml",
+			".github/sync-repo-settings.yaml",
+			"release-please-config.json",
+			".release-please-manifest.json",
+		} {
+			err := os.WriteFile(filepath.Join(repoDir, name), []byte("{}"), 0644)
+			assert.NoError(t, err)
+		}
+
+		files := locateReleasePleaseFiles(repoDir)
+		assert.Equal(t, filepath.Join(".github", "release-please.yaml"), files.Config)
+		assert.Equal(t, "release-please-config.json", files.ManifestConfig)
+		assert.Equal(t, ".release-please-manifest.json", files.Manifest)
+		assert.Equal(t, filepath.Join(".github", "sync-repo-settings.yaml"), files.SyncRepoSettings)
+		assert.Len(t, files.all(), 4)
+		assert.Equal(t, filepath.Join(repoDir, ".github", "release-please.yaml"), files.path(files.Config))
+	})
+
+	t.Run("prefers yml over yaml", func(t *testing.T) {
+		repoDir, err := os.MkdirTemp("", "repo")
+		assert.NoError(t, err)
+		defer os.RemoveAll(repoDir)
+
+		os.Mkdir(filepath.Join(repoDir, ".github"), 0755)
+		for _, name := range []string{".github/release-please.yml", ".github/release-please.yaml"} {
+			err := os.WriteFile(filepath.Join(repoDir, name), []byte(""), 0644)
+			assert.NoError(t, err)
+		}
+
+		files := locateReleasePleaseFiles(repoDir)
+		assert.Equal(t, filepath.Join(".github", "release-please.yml"), files.Config)
+		assert.Empty(t, files.Manifest)
+		assert.Equal(t, []string{filepath.Join(".github", "release-please.yml")}, files.all())
+	})
+}
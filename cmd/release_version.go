@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// isShallowRepo reports whether repoDir is a shallow clone, as produced by
+// "clone --depth 1".
+func isShallowRepo(repoDir string) (bool, error) {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
+	cmd.Dir = repoDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("error checking for shallow clone: %w, %s", err, string(output))
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+// fetchTags fetches all tags from origin without deepening the history of
+// the checked out branch.
+func fetchTags(repoDir string) error {
+	cmd := exec.Command("git", "fetch", "origin", "--tags", "--depth=1")
+	cmd.Dir = repoDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error fetching tags: %w, %s", err, string(output))
+	}
+	return nil
+}
+
+// listTags returns all tag names in repoDir.
+func listTags(repoDir string) ([]string, error) {
+	cmd := exec.Command("git", "tag", "--list")
+	cmd.Dir = repoDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags: %w, %s", err, string(output))
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// latestReleaseTag returns the highest non pre-release tag for component.
+// An empty component selects tags without a prefix, such as v1.2.3. The
+// returned tag is empty if no matching tag exists.
+func latestReleaseTag(tags []string, component string) (string, version) {
+	var latestTag string
+	var latest version
+	for _, tag := range tags {
+		c, v, err := parseTag(tag)
+		if err != nil || c != component || v.Prerelease != "" {
+			continue
+		}
+		if latestTag == "" || v.compare(latest) > 0 {
+			latestTag = tag
+			latest = v
+		}
+	}
+	return latestTag, latest
+}
+
+// manifestVersion reads the root package version from
+// .release-please-manifest.json. A manifest with a single package is used
+// regardless of its path.
+func manifestVersion(repoDir string) (string, error) {
+	files := locateReleasePleaseFiles(repoDir)
+	if files.Manifest == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(files.path(files.Manifest))
+	if err != nil {
+		return "", err
+	}
+	return parseManifestVersion(data)
+}
+
+// parseManifestVersion returns the root package version of a
+// .release-please-manifest.json, or the only package's version.
+func parseManifestVersion(data []byte) (string, error) {
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("error unmarshalling manifest: %w", err)
+	}
+	if v, ok := manifest["."]; ok {
+		return v, nil
+	}
+	if len(manifest) == 1 {
+		for _, v := range manifest {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+// getLatestVersion determines the latest released version of repoDir. Tags
+// are fetched first if the clone is shallow, which leaves the working tree
+// alone, so check mode sees the same tags as a real run. The release-please
+// manifest is used when no matching tag exists. The returned source
+// describes where the version came from and is empty if no version could
+// be determined.
+func getLatestVersion(repoDir, component string) (version, string, error) {
+	shallow, err := isShallowRepo(repoDir)
+	if err != nil {
+		return version{}, "", err
+	}
+	if shallow {
+		if err := fetchTags(repoDir); err != nil {
+			return version{}, "", err
+		}
+	}
+
+	tags, err := listTags(repoDir)
+	if err != nil {
+		return version{}, "", err
+	}
+	if tag, v := latestReleaseTag(tags, component); tag != "" {
+		return v, "tag " + tag, nil
+	}
+
+	manifest, err := manifestVersion(repoDir)
+	if err != nil {
+		return version{}, "", err
+	}
+	if manifest == "" {
+		return version{}, "", nil
+	}
+	v, err := parseVersion(manifest)
+	if err != nil {
+		return version{}, "", err
+	}
+	return v, "manifest version " + manifest, nil
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// repoEntry is a repository listed in github_repositories.txt. Each line
+// holds owner/name, optionally followed by whitespace and the branch used
+// for that repository:
+//
+//	googleapis/java-storage
+//	googleapis/java-bigtable protobuf-4.x-rc
+//
+// Blank lines and lines starting with # are ignored.
+type repoEntry struct {
+	Owner  string
+	Name   string
+	Branch string
+}
+
+// FullName returns owner/name.
+func (r repoEntry) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// Dir returns the directory the repository is cloned into.
+func (r repoEntry) Dir() string {
+	return filepath.Base(r.Name)
+}
+
+// URL returns the GitHub clone URL of the repository.
+func (r repoEntry) URL() string {
+	return fmt.Sprintf("https://github.com/%s.git", r.FullName())
+}
+
+// BranchOr returns the repository's branch, or def if none is listed.
+func (r repoEntry) BranchOr(def string) string {
+	if r.Branch != "" {
+		return r.Branch
+	}
+	return def
+}
+
+// parseRepoEntry parses a single line of github_repositories.txt.
+func parseRepoEntry(line string) (repoEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields) > 2 {
+		return repoEntry{}, fmt.Errorf("invalid repository line %q", line)
+	}
+	parts := strings.Split(fields[0], "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return repoEntry{}, fmt.Errorf("invalid repository %q, expected owner/repo", fields[0])
+	}
+	entry := repoEntry{Owner: parts[0], Name: parts[1]}
+	if len(fields) == 2 {
+		entry.Branch = fields[1]
+	}
+	return entry, nil
+}
+
+// readRepos reads the repositories listed in path.
+func readRepos(path string) ([]repoEntry, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []repoEntry
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := parseRepoEntry(line)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, entry)
+	}
+	return repos, nil
+}
+
+// selectRepos returns the repositories whose name or owner/name is in
+// names, in the order they are listed. All repositories are returned when
+// names is empty.
+func selectRepos(repos []repoEntry, names []string) ([]repoEntry, error) {
+	if len(names) == 0 {
+		return repos, nil
+	}
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var selected []repoEntry
+	for _, repo := range repos {
+		if wanted[repo.Name] || wanted[repo.FullName()] {
+			selected = append(selected, repo)
+			delete(wanted, repo.Name)
+			delete(wanted, repo.FullName())
+		}
+	}
+	for name := range wanted {
+		return nil, fmt.Errorf("repository %q is not in the repository list", name)
+	}
+	return selected, nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var scanProtobufCmd = &cobra.Command{
+	Use:   "scan-protobuf",
+	Short: "Find uses of protobuf APIs that break in protobuf 4.x",
+	Long: `Find uses of protobuf APIs that break in protobuf 4.x.
+
+Every .java file of each cloned repository, generated code included, is
+matched line by line against a list of known-breaking protobuf APIs. Each
+use is printed as file:line, followed by a summary of the counts per
+repository. The list of APIs is read from --apis, or protobuf-apis.yaml if
+present, in the format
+
+  apis:
+    - name: PARSER
+      pattern: '\b[A-Z]\w*\.PARSER\b'
+      description: public PARSER fields are deprecated, use parser()
+
+and defaults to a built-in list. The command exits with a non-zero status if
+any repository could not be scanned.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		names, _ := cmd.Flags().GetStringSlice("repos")
+		apisFile, _ := cmd.Flags().GetString("apis")
+		summary, _ := cmd.Flags().GetBool("summary")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+
+		apis, err := loadProtobufAPIs(apisFile)
+		if err != nil {
+			fmt.Println("Error reading protobuf APIs:", err)
+			return
+		}
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		repos, err = selectRepos(repos, names)
+		if err != nil {
+			fmt.Println("Error selecting repositories:", err)
+			return
+		}
+
+		results := runPool(repos, jobs, func(repo repoEntry) scanResult {
+			usages, err := scanRepoProtobuf(repo.Dir(), apis)
+			return scanResult{Repo: repo, Usages: usages, Err: err}
+		})
+		if !summary {
+			for _, r := range results {
+				for _, u := range r.Usages {
+					fmt.Printf("%s:%d: [%s] %s\n", u.Path, u.Line, u.API, u.Text)
+				}
+			}
+			fmt.Println()
+		}
+		if printScanSummary(results, apis) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scanProtobufCmd)
+	scanProtobufCmd.Flags().StringSlice("repos", nil, "Only scan these repositories (name or owner/name)")
+	scanProtobufCmd.Flags().String("apis", "", "YAML file of protobuf APIs to look for (default protobuf-apis.yaml if present, else built-in list)")
+	scanProtobufCmd.Flags().Bool("summary", false, "Only print the counts per repository")
+	scanProtobufCmd.Flags().IntP("jobs", "j", 8, "Number of repositories to scan in parallel")
+}
+
+// apiUsage is a line using a known-breaking protobuf API.
+type apiUsage struct {
+	Path string
+	Line int
+	API  string
+	Text string
+}
+
+// scanResult is the outcome of scanning a single repository.
+type scanResult struct {
+	Repo   repoEntry
+	Usages []apiUsage
+	Err    error
+}
+
+// scanRepoProtobuf returns the uses of apis in the Java sources of repoDir.
+func scanRepoProtobuf(repoDir string, apis []protobufAPI) ([]apiUsage, error) {
+	files, err := findFiles(repoDir, func(name string) bool { return strings.HasSuffix(name, ".java") })
+	if err != nil {
+		return nil, err
+	}
+	var usages []apiUsage
+	for _, path := range files {
+		found, err := scanJavaFile(path, apis)
+		if err != nil {
+			return usages, err
+		}
+		usages = append(usages, found...)
+	}
+	return usages, nil
+}
+
+// scanJavaFile returns the uses of apis in a single file. A line matching
+// several APIs is reported once for each.
+func scanJavaFile(path string, apis []protobufAPI) ([]apiUsage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var usages []apiUsage
+	scanner := bufio.NewScanner(f)
+	// Generated sources can have very long lines, such as serialized
+	// descriptors.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, api := range apis {
+			if api.re.MatchString(text) {
+				usages = append(usages, apiUsage{Path: path, Line: line, API: api.Name, Text: strings.TrimSpace(text)})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return usages, nil
+}
+
+// printScanSummary prints the counts per repository and reports whether any
+// repository could not be scanned.
+func printScanSummary(results []scanResult, apis []protobufAPI) bool {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tTOTAL\tAPIS")
+	total := 0
+	failed := false
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s\terror: %v\n", r.Repo.Dir(), r.Err)
+			failed = true
+			continue
+		}
+		counts := make(map[string]int)
+		for _, u := range r.Usages {
+			counts[u.API]++
+		}
+		var parts []string
+		for _, api := range apis {
+			if counts[api.Name] > 0 {
+				parts = append(parts, fmt.Sprintf("%s=%d", api.Name, counts[api.Name]))
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", r.Repo.Dir(), len(r.Usages), strings.Join(parts, ", "))
+		total += len(r.Usages)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t\n", total)
+	w.Flush()
+	return failed
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanRepoProtobuf(t *testing.T) {
+	repoDir := t.TempDir()
+	source := `package com.google.cloud;
+
+public final class Foo extends com.google.protobuf.GeneratedMessageV3 {
+  Bar bar = Bar.PARSER.parseFrom(bytes);
+  String s = TextFormat.printToString(bar);
+  String ok = TextFormat.printer().printToString(bar);
+}
+`
+	path := filepath.Join(repoDir, "src", "main", "java", "Foo.java")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(source), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, "target"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "target", "Foo.java"), []byte(source), 0644))
+
+	apis, err := loadProtobufAPIs("")
+	assert.NoError(t, err)
+	usages, err := scanRepoProtobuf(repoDir, apis)
+	assert.NoError(t, err)
+	assert.Equal(t, []apiUsage{
+		{Path: path, Line: 3, API: "GeneratedMessageV3", Text: "public final class Foo extends com.google.protobuf.GeneratedMessageV3 {"},
+		{Path: path, Line: 4, API: "PARSER", Text: "Bar bar = Bar.PARSER.parseFrom(bytes);"},
+		{Path: path, Line: 5, API: "TextFormat.print", Text: "String s = TextFormat.printToString(bar);"},
+	}, usages)
+	repo := repoEntry{Owner: "googleapis", Name: "java-storage"}
+	assert.False(t, printScanSummary([]scanResult{{Repo: repo, Usages: usages}}, apis))
+	_, err = scanRepoProtobuf(filepath.Join(repoDir, "missing"), apis)
+	assert.Error(t, err)
+	assert.True(t, printScanSummary([]scanResult{{Repo: repo, Usages: usages}, {Repo: repo, Err: err}}, apis))
+}
+
+func TestLoadProtobufAPIs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.WriteFile("protobuf-apis.yaml", []byte(`apis:
+  - name: RpcUtil
+    pattern: '\bRpcUtil\b'
+    description: removed
+`), 0644)
+	assert.NoError(t, err)
+
+	apis, err := loadProtobufAPIs("")
+	assert.NoError(t, err)
+	assert.Len(t, apis, 1)
+	assert.True(t, apis[0].re.MatchString("RpcUtil.specializeCallback(done)"))
+
+	assert.NoError(t, os.WriteFile("bad.yaml", []byte("apis:\n  - name: bad\n    pattern: '('\n"), 0644))
+	_, err = loadProtobufAPIs("bad.yaml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+var rcRegexp = regexp.MustCompile(`^rc\.?(\d+)$`)
+
+// version is a parsed semantic version such as 2.50.0 or 2.50.0-rc1.
+type version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// parseVersion parses a semantic version with an optional leading "v".
+// Build metadata is accepted but discarded.
+func parseVersion(s string) (version, error) {
+	m := versionRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return version{}, fmt.Errorf("invalid version %q", s)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	return version{Major: major, Minor: minor, Patch: patch, Prerelease: m[4]}, nil
+}
+
+func (v version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// rcNumber returns N if the version is a release candidate of the form
+// X.Y.Z-rcN (or X.Y.Z-rc.N).
+func (v version) rcNumber() (int, bool) {
+	m := rcRegexp.FindStringSubmatch(v.Prerelease)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// compare returns -1, 0 or 1 depending on whether v sorts before, equal to
+// or after o. Pre-release versions sort before the corresponding release.
+func (v version) compare(o version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease compares dot-separated pre-release identifiers. Numeric
+// suffixes such as the 10 in rc10 are compared numerically.
+func comparePrerelease(a, b string) int {
+	if n, ok := (version{Prerelease: a}).rcNumber(); ok {
+		if m, ok := (version{Prerelease: b}).rcNumber(); ok {
+			switch {
+			case n < m:
+				return -1
+			case n > m:
+				return 1
+			}
+			return 0
+		}
+	}
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// nextRCVersion computes the release candidate that follows current. A
+// release candidate has its RC number incremented; any other version is
+// bumped by the given component ("major", "minor", "patch" or "none") and
+// becomes rc1.
+func nextRCVersion(current, bump string) (string, error) {
+	v, err := parseVersion(current)
+	if err != nil {
+		return "", err
+	}
+	if n, ok := v.rcNumber(); ok {
+		v.Prerelease = fmt.Sprintf("rc%d", n+1)
+		return v.String(), nil
+	}
+	if v.Prerelease != "" {
+		return "", fmt.Errorf("cannot derive a release candidate from pre-release version %q", current)
+	}
+	switch bump {
+	case "major":
+		v = version{Major: v.Major + 1}
+	case "minor":
+		v = version{Major: v.Major, Minor: v.Minor + 1}
+	case "patch":
+		v.Patch++
+	case "none":
+	default:
+		return "", fmt.Errorf("unknown bump %q, expected major, minor, patch or none", bump)
+	}
+	v.Prerelease = "rc1"
+	return v.String(), nil
+}
+
+var tagRegexp = regexp.MustCompile(`^(?:(.+?)[-_/@])?(v?\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)$`)
+
+// parseTag splits a release tag into its component prefix and version.
+// It understands plain tags (v1.2.3, 1.2.3-rc1) as well as component
+// tags used by monorepos (google-cloud-storage-v2.1.0).
+func parseTag(tag string) (string, version, error) {
+	m := tagRegexp.FindStringSubmatch(strings.TrimSpace(tag))
+	if m == nil {
+		return "", version{}, fmt.Errorf("tag %q does not contain a version", tag)
+	}
+	v, err := parseVersion(m[2])
+	if err != nil {
+		return "", version{}, err
+	}
+	return m[1], v, nil
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var setDependencyCmd = &cobra.Command{
+	Use:   "set-dependency",
+	Short: "Set the version of a dependency group in every Maven and Gradle build file",
+	Long: `Set the version of every artifact in a Maven group (com.google.protobuf
+by default) in each cloned repository's pom.xml files, Gradle build scripts,
+gradle.properties and Gradle version catalogs. Literal versions, the
+properties and variables they reference and properties given with
+--property are rewritten in place, preserving the rest of the file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rewrite := dependencyRewrite{}
+		rewrite.Version, _ = cmd.Flags().GetString("version")
+		rewrite.GroupID, _ = cmd.Flags().GetString("group-id")
+		rewrite.ArtifactID, _ = cmd.Flags().GetString("artifact-id")
+		rewrite.Properties, _ = cmd.Flags().GetStringSlice("property")
+		check, _ := cmd.Flags().GetBool("check")
+		if rewrite.Version == "" {
+			fmt.Println("--version is required")
+			os.Exit(1)
+		}
+		setDependency(rewrite, check)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setDependencyCmd)
+	setDependencyCmd.Flags().String("version", "", "The version to set")
+	setDependencyCmd.Flags().String("group-id", "com.google.protobuf", "The groupId of the artifacts to update")
+	setDependencyCmd.Flags().String("artifact-id", "", "Only update this artifact of the group")
+	setDependencyCmd.Flags().StringSlice("property", []string{"protobuf.version", "protobufVersion"}, "Version properties or variables to update in addition to those referenced by matching artifacts")
+	setDependencyCmd.Flags().Bool("check", false, "Report files that would change and print a diff without writing them")
+}
+
+func setDependency(rewrite dependencyRewrite, check bool) {
+	repos, err := readRepos("github_repositories.txt")
+	if err != nil {
+		fmt.Println("Error reading repositories file:", err)
+		return
+	}
+
+	var changed []string
+	for _, repo := range repos {
+		repoDir := repo.Dir()
+		if _, err := os.Stat(repoDir); err != nil {
+			fmt.Printf("Skipping '%s': not cloned\n", repoDir)
+			continue
+		}
+		repoChanged, err := setRepoDependency(repoDir, rewrite, check)
+		if err != nil {
+			fmt.Printf("Error updating %s: %v\n", repoDir, err)
+		}
+		if repoChanged {
+			changed = append(changed, repoDir)
+		}
+	}
+
+	if check {
+		reportCheck(changed)
+	}
+}
+
+// buildFileFormat describes how to find and rewrite one kind of build
+// file.
+type buildFileFormat struct {
+	// scope groups the formats that share properties: a property referenced
+	// in one file is only rewritten in files of the same scope.
+	scope string
+	match func(name string) bool
+	// refs returns the properties referenced by matching artifacts.
+	refs    func(content string, rewrite dependencyRewrite) []string
+	rewrite func(content string, rewrite dependencyRewrite, properties []string) (string, []lineChange)
+}
+
+var buildFileFormats = []buildFileFormat{
+	{
+		scope:   "maven",
+		match:   func(name string) bool { return name == "pom.xml" },
+		refs:    pomPropertyRefs,
+		rewrite: rewritePOM,
+	},
+	{
+		scope: "gradle",
+		match: func(name string) bool {
+			return strings.HasSuffix(name, ".gradle") || strings.HasSuffix(name, ".gradle.kts")
+		},
+		refs:    gradlePropertyRefs,
+		rewrite: rewriteGradle,
+	},
+	{
+		scope:   "gradle",
+		match:   func(name string) bool { return name == "gradle.properties" },
+		rewrite: rewriteGradleProperties,
+	},
+	{
+		scope:   "catalog",
+		match:   func(name string) bool { return strings.HasSuffix(name, ".versions.toml") },
+		refs:    catalogPropertyRefs,
+		rewrite: rewriteCatalog,
+	},
+}
+
+// buildFile is a build file read from a repository.
+type buildFile struct {
+	path    string
+	content string
+	format  buildFileFormat
+}
+
+// setRepoDependency rewrites the Maven and Gradle build files of one
+// repository. Properties referenced by matching artifacts are collected
+// across all build files of a scope first, since a module usually uses a
+// property defined in its parent. It reports whether any file changed or
+// would change, and stops at the first file that cannot be read or written.
+func setRepoDependency(repoDir string, rewrite dependencyRewrite, check bool) (bool, error) {
+	var files []buildFile
+	for _, format := range buildFileFormats {
+		paths, err := findFiles(repoDir, format.match)
+		if err != nil {
+			return false, err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return false, err
+			}
+			files = append(files, buildFile{path: path, content: string(data), format: format})
+		}
+	}
+
+	// Referenced properties are only shared between files of the same
+	// scope, so that a Maven property is never applied to a Gradle script.
+	properties := make(map[string][]string)
+	seen := make(map[[2]string]bool)
+	add := func(scope, property string) {
+		if !seen[[2]string{scope, property}] {
+			seen[[2]string{scope, property}] = true
+			properties[scope] = append(properties[scope], property)
+		}
+	}
+	for _, format := range buildFileFormats {
+		for _, property := range rewrite.Properties {
+			add(format.scope, property)
+		}
+	}
+	for _, file := range files {
+		if file.format.refs == nil {
+			continue
+		}
+		for _, ref := range file.format.refs(file.content, rewrite) {
+			add(file.format.scope, ref)
+		}
+	}
+
+	changed := false
+	for _, file := range files {
+		updated, changes := file.format.rewrite(file.content, rewrite, properties[file.format.scope])
+		fileChanged, err := writeDependencyChanges(file.path, updated, changes, check)
+		if fileChanged {
+			changed = true
+		}
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// writeDependencyChanges reports each change in path and writes the updated
+// content unless in check mode. It reports whether the file changed.
+func writeDependencyChanges(path, content string, changes []lineChange, check bool) (bool, error) {
+	if len(changes) == 0 {
+		return false, nil
+	}
+	fmt.Printf("Updating '%s'\n", path)
+	for _, c := range changes {
+		fmt.Printf("  line %d: %s -> %s\n", c.Line, c.Old, c.New)
+	}
+	changed, err := writeOrCheck(path, []byte(content), check)
+	if err != nil {
+		return changed, fmt.Errorf("error writing to %s: %w", path, err)
+	}
+	return changed, nil
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const parentPOM = `<project>
+  <properties>
+    <protobuf.version>3.25.5</protobuf.version>
+    <grpc.version>1.66.0</grpc.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>com.google.protobuf</groupId>
+        <artifactId>protobuf-bom</artifactId>
+        <version>3.25.5</version>
+        <type>pom</type>
+      </dependency>
+      <dependency>
+        <groupId>io.grpc</groupId>
+        <artifactId>grpc-protobuf</artifactId>
+        <version>${grpc.version}</version>
+        <exclusions>
+          <exclusion>
+            <groupId>com.google.protobuf</groupId>
+            <artifactId>protobuf-java</artifactId>
+          </exclusion>
+        </exclusions>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <build>
+    <plugins>
+      <plugin>
+        <configuration>
+          <protocArtifact>com.google.protobuf:protoc:3.25.5:exe:${os.detected.classifier}</protocArtifact>
+        </configuration>
+      </plugin>
+    </plugins>
+  </build>
+</project>
+`
+
+const modulePOM = `<project>
+  <properties>
+    <protobuf-java-util.version>3.25.5</protobuf-java-util.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>com.google.protobuf</groupId>
+      <artifactId>protobuf-java-util</artifactId>
+      <version>${protobuf-java-util.version}</version>
+    </dependency>
+  </dependencies>
+</project>
+`
+
+func TestRewritePOM(t *testing.T) {
+	rewrite := dependencyRewrite{GroupID: "com.google.protobuf", Version: "4.28.2"}
+	updated, changes := rewritePOM(parentPOM, rewrite, []string{"protobuf.version"})
+
+	assert.Equal(t, []lineChange{
+		{Line: 3, Old: "3.25.5", New: "4.28.2"},
+		{Line: 11, Old: "3.25.5", New: "4.28.2"},
+		{Line: 31, Old: "3.25.5", New: "4.28.2"},
+	}, changes)
+	assert.Contains(t, updated, "<protobuf.version>4.28.2</protobuf.version>")
+	assert.Contains(t, updated, "<grpc.version>1.66.0</grpc.version>")
+	assert.Contains(t, updated, "com.google.protobuf:protoc:4.28.2:exe:")
+
+	again, changes := rewritePOM(updated, rewrite, []string{"protobuf.version"})
+	assert.Empty(t, changes)
+	assert.Equal(t, updated, again)
+}
+
+func TestSetRepoDependency(t *testing.T) {
+	repoDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "pom.xml"), []byte(parentPOM), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, "module", "target"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "module", "pom.xml"), []byte(modulePOM), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "module", "target", "pom.xml"), []byte(modulePOM), 0644))
+
+	rewrite := dependencyRewrite{GroupID: "com.google.protobuf", Version: "4.28.2", Properties: []string{"protobuf.version"}}
+
+	changed, err := setRepoDependency(repoDir, rewrite, true)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	content, err := os.ReadFile(filepath.Join(repoDir, "module", "pom.xml"))
+	assert.NoError(t, err)
+	assert.Equal(t, modulePOM, string(content), "check mode must not write")
+
+	changed, err = setRepoDependency(repoDir, rewrite, false)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	content, err = os.ReadFile(filepath.Join(repoDir, "module", "pom.xml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "<protobuf-java-util.version>4.28.2</protobuf-java-util.version>")
+
+	content, err = os.ReadFile(filepath.Join(repoDir, "module", "target", "pom.xml"))
+	assert.NoError(t, err)
+	assert.Equal(t, modulePOM, string(content), "build output must be skipped")
+
+	changed, err = setRepoDependency(repoDir, rewrite, false)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestRewriteGradle(t *testing.T) {
+	content := `ext {
+    protobufVersion = '3.25.5'
+}
+dependencies {
+    implementation "com.google.protobuf:protobuf-java:$protobufVersion"
+    implementation 'com.google.protobuf:protobuf-java-util:3.25.5'
+    implementation group: 'com.google.protobuf', name: 'protobuf-javalite', version: '3.25.5'
+    implementation "io.grpc:grpc-protobuf:1.66.0"
+}
+protobuf {
+    protoc { artifact = "com.google.protobuf:protoc:3.25.5" }
+}
+`
+	rewrite := dependencyRewrite{GroupID: "com.google.protobuf", Version: "4.28.2"}
+	assert.Equal(t, []string{"protobufVersion"}, gradlePropertyRefs(content, rewrite))
+
+	updated, changes := rewriteGradle(content, rewrite, []string{"protobufVersion"})
+	lines := []int{}
+	for _, c := range changes {
+		lines = append(lines, c.Line)
+	}
+	assert.Equal(t, []int{2, 6, 7, 11}, lines)
+	assert.Contains(t, updated, "implementation \"io.grpc:grpc-protobuf:1.66.0\"")
+	assert.Contains(t, updated, "implementation \"com.google.protobuf:protobuf-java:$protobufVersion\"")
+
+	kts := `val protobufVersion by extra("3.25.5")
+dependencies {
+    implementation("com.google.protobuf:protobuf-kotlin:${rootProject.extra["protobufVersion"]}")
+}
+`
+	updated, changes = rewriteGradle(kts, rewrite, []string{"protobufVersion"})
+	assert.Len(t, changes, 1)
+	assert.Contains(t, updated, `val protobufVersion by extra("4.28.2")`)
+}
+
+func TestGradleVariableRef(t *testing.T) {
+	for version, want := range map[string]string{
+		"$protobufVersion":                   "protobufVersion",
+		"${protobufVersion}":                 "protobufVersion",
+		"${rootProject.ext.protobufVersion}": "protobufVersion",
+		"$version":                           "",
+		"${project.version}":                 "",
+		"${rootProject.ext.version}":         "",
+		"${libs.versions.protobuf.get()}":    "",
+		"${deps.protobuf}":                   "",
+		"3.25.5":                             "",
+	} {
+		name, _ := gradleVariableRef(version)
+		assert.Equal(t, want, name, version)
+	}
+}
+
+func TestSetRepoDependencyScopesProperties(t *testing.T) {
+	repoDir := t.TempDir()
+	pom := `<project>
+  <version>1.2.3</version>
+  <dependencies>
+    <dependency>
+      <groupId>com.google.protobuf</groupId>
+      <artifactId>protobuf-java</artifactId>
+      <version>${project.version}</version>
+    </dependency>
+  </dependencies>
+</project>
+`
+	gradle := `version = '1.2.3'
+ext.protoVersion = '3.25.5'
+dependencies {
+    implementation "com.google.protobuf:protobuf-java:$version"
+    implementation "com.google.protobuf:protobuf-java-util:$protoVersion"
+}
+`
+	catalog := `[versions]
+protobuf = "3.25.5"
+protoVersion = "0.9.4"
+
+[libraries]
+protobuf-java = { module = "com.google.protobuf:protobuf-java", version.ref = "protobuf" }
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "pom.xml"), []byte(pom), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "build.gradle"), []byte(gradle), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, "gradle"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "gradle", "libs.versions.toml"), []byte(catalog), 0644))
+
+	rewrite := dependencyRewrite{GroupID: "com.google.protobuf", Version: "4.28.2"}
+	changed, err := setRepoDependency(repoDir, rewrite, false)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	content, err := os.ReadFile(filepath.Join(repoDir, "pom.xml"))
+	assert.NoError(t, err)
+	assert.Equal(t, pom, string(content))
+	content, err = os.ReadFile(filepath.Join(repoDir, "build.gradle"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "version = '1.2.3'")
+	assert.Contains(t, string(content), "ext.protoVersion = '4.28.2'")
+	content, err = os.ReadFile(filepath.Join(repoDir, "gradle", "libs.versions.toml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `protobuf = "4.28.2"`)
+	assert.Contains(t, string(content), `protoVersion = "0.9.4"`, "Gradle variables must not leak into the catalog")
+}
+
+func TestRewriteCatalog(t *testing.T) {
+	content := `[versions]
+protobuf = "3.25.5"
+protobuf-plugin = "0.9.4"
+grpc = "1.66.0"
+
+[libraries]
+protobuf-java = { module = "com.google.protobuf:protobuf-java", version.ref = "protobuf" }
+protobuf-util = { group = "com.google.protobuf", name = "protobuf-java-util", version = "3.25.5" }
+protoc = "com.google.protobuf:protoc:3.25.5"
+grpc-protobuf = { module = "io.grpc:grpc-protobuf", version.ref = "grpc" }
+
+[plugins]
+protobuf = { id = "com.google.protobuf", version.ref = "protobuf-plugin" }
+`
+	rewrite := dependencyRewrite{GroupID: "com.google.protobuf", Version: "4.28.2"}
+	refs := catalogPropertyRefs(content, rewrite)
+	assert.Equal(t, []string{"protobuf"}, refs)
+
+	updated, changes := rewriteCatalog(content, rewrite, refs)
+	assert.Equal(t, []lineChange{
+		{Line: 2, Old: "3.25.5", New: "4.28.2"},
+		{Line: 8, Old: "3.25.5", New: "4.28.2"},
+		{Line: 9, Old: "3.25.5", New: "4.28.2"},
+	}, changes)
+	assert.Contains(t, updated, `protobuf-plugin = "0.9.4"`)
+	assert.Contains(t, updated, `grpc = "1.66.0"`)
+}
+
+func TestRewriteGradleProperties(t *testing.T) {
+	content := "org.gradle.jvmargs=-Xmx2g\nprotobufVersion = 3.25.5\n"
+	updated, changes := rewriteGradleProperties(content, dependencyRewrite{Version: "4.28.2"}, []string{"protobufVersion"})
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "org.gradle.jvmargs=-Xmx2g\nprotobufVersion = 4.28.2\n", updated)
+}
+
+func TestRewriteSingleArtifact(t *testing.T) {
+	rewrite := dependencyRewrite{GroupID: "com.google.protobuf", ArtifactID: "protobuf-bom", Version: "4.28.2"}
+	_, changes := rewritePOM(parentPOM, rewrite, nil)
+	assert.Equal(t, []lineChange{{Line: 11, Old: "3.25.5", New: "4.28.2"}}, changes)
+
+	gradle := `implementation 'com.google.protobuf:protobuf-java:3.25.5'
+implementation 'com.google.protobuf:protobuf-bom:3.25.5'
+`
+	_, changes = rewriteGradle(gradle, rewrite, nil)
+	assert.Equal(t, []lineChange{{Line: 2, Old: "3.25.5", New: "4.28.2"}}, changes)
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var setRCVersionCmd = &cobra.Command{
+	Use:   "set-rc-version",
+	Short: "Set release candidate versions in .release-please-manifest.json and versions.txt",
+	Run: func(cmd *cobra.Command, args []string) {
+		bump, _ := cmd.Flags().GetString("bump")
+		setRCVersions(bump)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setRCVersionCmd)
+	setRCVersionCmd.Flags().String("bump", "minor", "Version component to bump before the first RC: major, minor, patch or none")
+}
+
+func setRCVersions(bump string) {
+	repos, err := readRepos("github_repositories.txt")
+	if err != nil {
+		fmt.Println("Error reading repositories file:", err)
+		return
+	}
+
+	for _, repo := range repos {
+		repoDir := repo.Dir()
+		fmt.Printf("--- Setting RC version for %s ---\n", repoDir)
+		setRCVersion(repoDir, bump)
+	}
+}
+
+func setRCVersion(repoDir, bump string) {
+	files := locateReleasePleaseFiles(repoDir)
+	if files.Manifest == "" {
+		fmt.Printf("Skipping '%s': .release-please-manifest.json not found\n", repoDir)
+		return
+	}
+	manifestPath := files.path(files.Manifest)
+
+	changes, err := updateManifestVersions(manifestPath, bump)
+	if err != nil {
+		fmt.Printf("Error updating %s: %v\n", manifestPath, err)
+		return
+	}
+
+	// Each package owns the versions.txt in its directory. A monorepo's
+	// root versions.txt lists the modules of several packages, so it is only
+	// updated when the manifest has a root package.
+	packages := make([]string, 0, len(changes))
+	for pkg := range changes {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	for _, pkg := range packages {
+		versionsPath := filepath.Join(repoDir, pkg, "versions.txt")
+		if _, err := os.Stat(versionsPath); err != nil {
+			continue
+		}
+		if err := updateVersionsTxt(versionsPath, changes[pkg]); err != nil {
+			fmt.Printf("Error updating %s: %v\n", versionsPath, err)
+			return
+		}
+	}
+
+	fmt.Printf("Successfully set RC versions for %s\n", repoDir)
+}
+
+// versionChange is the old and new version of a release-please package.
+type versionChange struct {
+	From, To string
+}
+
+// updateManifestVersions rewrites every package version in a release-please
+// manifest to its next release candidate and returns the changes keyed by
+// package. The file is edited in place so that key order and formatting are
+// preserved.
+func updateManifestVersions(path, bump string) (map[string]versionChange, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("error unmarshalling %s: %w", path, err)
+	}
+
+	packages := make([]string, 0, len(manifest))
+	for pkg := range manifest {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+
+	changes := make(map[string]versionChange)
+	newContent := string(content)
+	for _, pkg := range packages {
+		current := manifest[pkg]
+		next, err := nextRCVersion(current, bump)
+		if err != nil {
+			return nil, fmt.Errorf("package %q: %w", pkg, err)
+		}
+		changes[pkg] = versionChange{From: current, To: next}
+
+		re := regexp.MustCompile(`("` + regexp.QuoteMeta(pkg) + `"\s*:\s*")` + regexp.QuoteMeta(current) + `"`)
+		newContent = re.ReplaceAllString(newContent, "${1}"+next+`"`)
+		fmt.Printf("  - %s: %s -> %s\n", pkg, current, next)
+	}
+
+	if err := os.WriteFile(path, []byte(newContent), 0644); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// updateVersionsTxt moves the modules of a package's versions.txt that were
+// released at change.From to change.To. Lines follow the
+// module:released-version:current-version format. A current version that is
+// a snapshot stays one, of the release candidate after change.To; otherwise
+// it is set to change.To like the released version. Modules versioned
+// separately from the package are left alone.
+func updateVersionsTxt(path string, change versionChange) error {
+	snapshot, err := nextRCVersion(change.To, "none")
+	if err != nil {
+		return err
+	}
+	snapshot += "-SNAPSHOT"
+
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		parts := strings.Split(trimmed, ":")
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] != change.From {
+			fmt.Printf("  - Leaving %s at %s, versioned separately from %s\n", parts[0], parts[1], change.From)
+			continue
+		}
+		next := change.To
+		if strings.HasSuffix(parts[2], "-SNAPSHOT") {
+			next = snapshot
+		}
+		lines[i] = fmt.Sprintf("%s:%s:%s", parts[0], change.To, next)
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextRCVersion(t *testing.T) {
+	tests := []struct {
+		current string
+		bump    string
+		want    string
+	}{
+		{"2.49.0", "minor", "2.50.0-rc1"},
+		{"2.49.3", "patch", "2.49.4-rc1"},
+		{"2.49.3", "major", "3.0.0-rc1"},
+		{"2.50.0", "none", "2.50.0-rc1"},
+		{"2.50.0-rc1", "minor", "2.50.0-rc2"},
+		{"2.50.0-rc9", "minor", "2.50.0-rc10"},
+	}
+	for _, tt := range tests {
+		got, err := nextRCVersion(tt.current, tt.bump)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "nextRCVersion(%q, %q)", tt.current, tt.bump)
+	}
+
+	_, err := nextRCVersion("2.50.0-beta", "minor")
+	assert.Error(t, err)
+	_, err = nextRCVersion("not-a-version", "minor")
+	assert.Error(t, err)
+}
+
+func TestSetRCVersion(t *testing.T) {
+	repoDir, err := os.MkdirTemp("", "repo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(repoDir)
+
+	manifestPath := filepath.Join(repoDir, ".release-please-manifest.json")
+	err = os.WriteFile(manifestPath, []byte("{\n  \".\": \"2.49.0\"\n}\n"), 0644)
+	assert.NoError(t, err)
+
+	versionsPath := filepath.Join(repoDir, "versions.txt")
+	versions := `# Format:
+# module:released-version:current-version
+
+google-cloud-storage:2.49.0:2.49.1-SNAPSHOT
+google-cloud-storage-bom:2.49.0:2.49.0
+grpc-google-cloud-storage-v2:2.49.0-beta:2.49.1-beta-SNAPSHOT
+`
+	err = os.WriteFile(versionsPath, []byte(versions), 0644)
+	assert.NoError(t, err)
+
+	setRCVersion(repoDir, "minor")
+
+	data, err := os.ReadFile(manifestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \".\": \"2.50.0-rc1\"\n}\n", string(data))
+
+	data, err = os.ReadFile(versionsPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "google-cloud-storage:2.50.0-rc1:2.50.0-rc2-SNAPSHOT")
+	assert.Contains(t, string(data), "google-cloud-storage-bom:2.50.0-rc1:2.50.0-rc1")
+	assert.Contains(t, string(data), "grpc-google-cloud-storage-v2:2.49.0-beta:2.49.1-beta-SNAPSHOT")
+
+	// A second run increments the RC number.
+	setRCVersion(repoDir, "minor")
+
+	data, err = os.ReadFile(manifestPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"2.50.0-rc2"`)
+
+	data, err = os.ReadFile(versionsPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "google-cloud-storage:2.50.0-rc2:2.50.0-rc3-SNAPSHOT")
+}
+
+func TestSetRCVersionMonorepo(t *testing.T) {
+	repoDir := t.TempDir()
+	manifest := "{\n  \"java-pubsub\": \"1.130.0\",\n  \"java-storage\": \"2.49.0\"\n}\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, ".release-please-manifest.json"), []byte(manifest), 0644))
+	rootVersions := "google-cloud-pubsub:1.130.0:1.130.1-SNAPSHOT\ngoogle-cloud-storage:2.49.0:2.49.1-SNAPSHOT\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "versions.txt"), []byte(rootVersions), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(repoDir, "java-storage"), 0755))
+	// google-cloud-storage-control shares the version of java-pubsub but
+	// belongs to java-storage.
+	storageVersions := "google-cloud-storage:2.49.0:2.49.1-SNAPSHOT\ngoogle-cloud-storage-control:1.130.0:1.130.1-SNAPSHOT\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "java-storage", "versions.txt"), []byte(storageVersions), 0644))
+
+	setRCVersion(repoDir, "minor")
+
+	data, err := os.ReadFile(filepath.Join(repoDir, "java-storage", "versions.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "google-cloud-storage:2.50.0-rc1:2.50.0-rc2-SNAPSHOT\ngoogle-cloud-storage-control:1.130.0:1.130.1-SNAPSHOT\n", string(data))
+
+	data, err = os.ReadFile(filepath.Join(repoDir, "versions.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, rootVersions, string(data))
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show branch, dirty state and ahead/behind counts for each repository",
+	Run: func(cmd *cobra.Command, args []string) {
+		branch, _ := cmd.Flags().GetString("branch")
+		base, _ := cmd.Flags().GetString("base")
+		outputJSON, _ := cmd.Flags().GetBool("output-json")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+
+		var statuses []repoStatus
+		for _, repo := range repos {
+			statuses = append(statuses, collectRepoStatus(repo.Dir(), repo.BranchOr(branch), base))
+		}
+
+		if outputJSON {
+			jsonOutput, err := json.MarshalIndent(statuses, "", "  ")
+			if err != nil {
+				fmt.Println("Error marshalling to JSON:", err)
+				return
+			}
+			fmt.Println(string(jsonOutput))
+			return
+		}
+		printRepoStatuses(statuses)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "Branch to compare against for repositories that do not list one")
+	statusCmd.Flags().String("base", "main", "Base branch to compare against")
+	statusCmd.Flags().BoolP("output-json", "j", false, "Output in JSON format")
+}
+
+// aheadBehindCount is the number of commits HEAD is ahead of and behind a
+// remote branch.
+type aheadBehindCount struct {
+	Ref    string `json:"ref"`
+	Ahead  int    `json:"ahead"`
+	Behind int    `json:"behind"`
+}
+
+// repoStatus is the state of a single cloned repository.
+type repoStatus struct {
+	Repo      string `json:"repo"`
+	Branch    string `json:"branch"`
+	Head      string `json:"head"`
+	Modified  int    `json:"modified"`
+	Untracked int    `json:"untracked"`
+	Stashes   int    `json:"stashes"`
+	Shallow   bool   `json:"shallow"`
+	// Tracking and Base are nil when the remote branch does not exist.
+	Tracking *aheadBehindCount `json:"tracking,omitempty"`
+	Base     *aheadBehindCount `json:"base,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// collectRepoStatus gathers the status of repoDir, comparing HEAD with
+// origin/<branch> and origin/<base>.
+func collectRepoStatus(repoDir, branch, base string) repoStatus {
+	status := repoStatus{Repo: repoDir}
+	if !isGitRepo(repoDir) {
+		status.Error = "not a git repository"
+		return status
+	}
+
+	var err error
+	if status.Branch, err = runGit(repoDir, "rev-parse", "--abbrev-ref", "HEAD"); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if status.Head, err = runGit(repoDir, "rev-parse", "HEAD"); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	porcelain, err := runGit(repoDir, "status", "--porcelain")
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	for _, line := range strings.Split(porcelain, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "??"):
+			status.Untracked++
+		default:
+			status.Modified++
+		}
+	}
+
+	if stashes, err := runGit(repoDir, "stash", "list"); err == nil && stashes != "" {
+		status.Stashes = len(strings.Split(stashes, "\n"))
+	}
+	status.Shallow, _ = isShallowRepo(repoDir)
+
+	status.Tracking = countAheadBehind(repoDir, "origin/"+branch)
+	status.Base = countAheadBehind(repoDir, "origin/"+base)
+	return status
+}
+
+// countAheadBehind compares HEAD with ref, returning nil if ref is missing.
+func countAheadBehind(repoDir, ref string) *aheadBehindCount {
+	ahead, behind, err := aheadBehind(repoDir, ref)
+	if err != nil {
+		return nil
+	}
+	return &aheadBehindCount{Ref: ref, Ahead: ahead, Behind: behind}
+}
+
+func printRepoStatuses(statuses []repoStatus) {
+	formatCount := func(c *aheadBehindCount) string {
+		if c == nil {
+			return "-"
+		}
+		return fmt.Sprintf("+%d/-%d", c.Ahead, c.Behind)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tBRANCH\tHEAD\tDIRTY\tUNTRACKED\tTRACKING\tBASE\tSTASH\tSHALLOW")
+	for _, s := range statuses {
+		if s.Error != "" {
+			fmt.Fprintf(w, "%s\terror: %s\n", s.Repo, strings.SplitN(s.Error, "\n", 2)[0])
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\t%d\t%s\n",
+			s.Repo, s.Branch, shortSHA(s.Head), s.Modified, s.Untracked,
+			formatCount(s.Tracking), formatCount(s.Base), s.Stashes, strconv.FormatBool(s.Shallow))
+	}
+	w.Flush()
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectRepoStatus(t *testing.T) {
+	originDir, err := os.MkdirTemp("", "origin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(originDir)
+	runGitT(t, originDir, "init", "--bare")
+
+	repoDir, err := os.MkdirTemp("", "repo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(repoDir)
+	setupGitRepo(t, repoDir)
+	createCommit(t, repoDir, "initial commit")
+	runGitT(t, repoDir, "remote", "add", "origin", originDir)
+	runGitT(t, repoDir, "push", "origin", "HEAD:refs/heads/main", "HEAD:refs/heads/protobuf-4.x-rc")
+	runGitT(t, repoDir, "fetch", "origin")
+	runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc")
+
+	createCommit(t, repoDir, "local commit")
+	err = os.WriteFile(filepath.Join(repoDir, "test.txt"), []byte("modified"), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(repoDir, "new.txt"), []byte("untracked"), 0644)
+	assert.NoError(t, err)
+
+	status := collectRepoStatus(repoDir, "protobuf-4.x-rc", "main")
+	assert.Empty(t, status.Error)
+	assert.Equal(t, "protobuf-4.x-rc", status.Branch)
+	assert.Len(t, status.Head, 40)
+	assert.Equal(t, 1, status.Modified)
+	assert.Equal(t, 1, status.Untracked)
+	assert.Equal(t, 0, status.Stashes)
+	assert.False(t, status.Shallow)
+	assert.Equal(t, &aheadBehindCount{Ref: "origin/protobuf-4.x-rc", Ahead: 1}, status.Tracking)
+	assert.Equal(t, &aheadBehindCount{Ref: "origin/main", Ahead: 1}, status.Base)
+
+	status = collectRepoStatus(repoDir, "missing", "main")
+	assert.Nil(t, status.Tracking)
+
+	status = collectRepoStatus(filepath.Join(repoDir, "nope"), "main", "main")
+	assert.Equal(t, "not a git repository", status.Error)
+}
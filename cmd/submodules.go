@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var submodulesCmd = &cobra.Command{
+	Use:   "submodules",
+	Short: "Manage the repository submodules of this repository",
+}
+
+func init() {
+	rootCmd.AddCommand(submodulesCmd)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var submodulesReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Make .gitmodules match github_repositories.txt",
+	Long: `Make .gitmodules match github_repositories.txt.
+
+Submodules of repositories that are no longer listed are deinitialized and
+removed together with their git directory under .git/modules. Listed
+repositories without a submodule are added, and submodules whose URL or
+branch differs from the list are updated. The changes are staged but not
+committed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		branch, _ := cmd.Flags().GetString("branch")
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		if err := reconcileSubmodules(".", repos, branch, force, dryRun); err != nil {
+			fmt.Println("Error reconciling submodules:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	submodulesCmd.AddCommand(submodulesReconcileCmd)
+	submodulesReconcileCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "Branch to track for repositories that do not list one")
+	submodulesReconcileCmd.Flags().Bool("force", false, "Remove or replace directories even if they have uncommitted or unpushed work")
+	submodulesReconcileCmd.Flags().Bool("dry-run", false, "Only print the changes that would be made")
+}
+
+// reconcileSubmodules brings the submodules of parentDir in line with repos.
+// Submodules that fail to update are reported and skipped, and an error
+// naming them is returned once the rest have been reconciled.
+func reconcileSubmodules(parentDir string, repos []repoEntry, defaultBranch string, force, dryRun bool) error {
+	submodules, err := readGitmodules(parentDir)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]repoEntry)
+	for _, repo := range repos {
+		wanted[repo.Dir()] = repo
+	}
+
+	var stale []string
+	for path := range submodules {
+		if _, ok := wanted[path]; !ok {
+			stale = append(stale, path)
+		}
+	}
+	sort.Strings(stale)
+
+	var failed []string
+	for _, path := range stale {
+		sm := submodules[path]
+		fmt.Printf("Removing stale submodule %s\n", path)
+		if dryRun {
+			continue
+		}
+		if err := removeSubmodule(parentDir, sm, force); err != nil {
+			fmt.Printf("Error removing %s: %v\n", path, err)
+			failed = append(failed, path)
+		}
+	}
+
+	for _, repo := range repos {
+		branch := repo.BranchOr(defaultBranch)
+		sm, ok := submodules[repo.Dir()]
+		if !ok {
+			fmt.Printf("Adding missing submodule %s\n", repo.Dir())
+			if dryRun {
+				continue
+			}
+			if err := addSubmodule(parentDir, repo, branch, force); err != nil {
+				fmt.Printf("Error adding %s: %v\n", repo.Dir(), err)
+				failed = append(failed, repo.Dir())
+			}
+			continue
+		}
+
+		reconciled := true
+		if !sameRepoURL(sm.URL, repo.URL()) {
+			fmt.Printf("Updating URL of %s: %s -> %s\n", sm.Path, sm.URL, repo.URL())
+			if !dryRun {
+				if _, err := runGit(parentDir, "submodule", "set-url", "--", sm.Path, repo.URL()); err != nil {
+					fmt.Printf("Error updating URL of %s: %v\n", sm.Path, err)
+					reconciled = false
+				}
+			}
+		}
+		if sm.Branch != branch {
+			fmt.Printf("Updating branch of %s: %s -> %s\n", sm.Path, sm.Branch, branch)
+			if !dryRun {
+				if _, err := runGit(parentDir, "submodule", "set-branch", "--branch", branch, "--", sm.Path); err != nil {
+					fmt.Printf("Error updating branch of %s: %v\n", sm.Path, err)
+					reconciled = false
+				}
+			}
+		}
+		if !reconciled {
+			failed = append(failed, sm.Path)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not reconcile %s", strings.Join(failed, ", "))
+	}
+	fmt.Println("Submodules reconciled.")
+	return nil
+}
+
+// removeSubmodule deinitializes sm and removes it from the index, the
+// worktree and .git/modules.
+func removeSubmodule(parentDir string, sm submodule, force bool) error {
+	path := filepath.Join(parentDir, sm.Path)
+	if isGitRepo(path) && !force {
+		if err := checkDiscardable(path, sm.Branch); err != nil {
+			return fmt.Errorf("refusing to remove %s: %w (use --force to override)", sm.Path, err)
+		}
+	}
+
+	if _, err := runGit(parentDir, "submodule", "deinit", "-f", "--", sm.Path); err != nil {
+		return err
+	}
+	if _, err := runGit(parentDir, "rm", "-f", "--", sm.Path); err != nil {
+		return err
+	}
+	gitDir, err := runGit(parentDir, "rev-parse", "--git-dir")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(parentDir, gitDir)
+	}
+	return os.RemoveAll(filepath.Join(gitDir, "modules", sm.Name))
+}
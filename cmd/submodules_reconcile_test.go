@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileSubmodules(t *testing.T) {
+	t.Run("removes stale submodules", func(t *testing.T) {
+		_, parentDir := setupSubmodule(t)
+
+		err := reconcileSubmodules(parentDir, nil, "protobuf-4.x-rc", false, false)
+		assert.NoError(t, err)
+
+		submodules, err := readGitmodules(parentDir)
+		assert.NoError(t, err)
+		assert.Empty(t, submodules)
+		_, err = os.Stat(filepath.Join(parentDir, "lib"))
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(parentDir, ".git", "modules", "lib"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("keeps stale submodules with unpushed work", func(t *testing.T) {
+		_, parentDir := setupSubmodule(t)
+		libDir := filepath.Join(parentDir, "lib")
+		runGitT(t, libDir, "config", "user.email", "test@example.com")
+		runGitT(t, libDir, "config", "user.name", "Test User")
+		createCommit(t, libDir, "local only")
+
+		err := reconcileSubmodules(parentDir, nil, "protobuf-4.x-rc", false, false)
+		assert.ErrorContains(t, err, "lib")
+
+		submodules, err := readGitmodules(parentDir)
+		assert.NoError(t, err)
+		assert.Contains(t, submodules, "lib")
+	})
+
+	t.Run("fixes url and branch", func(t *testing.T) {
+		_, parentDir := setupSubmodule(t)
+		repos := []repoEntry{{Owner: "googleapis", Name: "lib"}}
+
+		err := reconcileSubmodules(parentDir, repos, "protobuf-4.x-rc", false, false)
+		assert.NoError(t, err)
+
+		submodules, err := readGitmodules(parentDir)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://github.com/googleapis/lib.git", submodules["lib"].URL)
+		assert.Equal(t, "protobuf-4.x-rc", submodules["lib"].Branch)
+	})
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		_, parentDir := setupSubmodule(t)
+		before, err := os.ReadFile(filepath.Join(parentDir, ".gitmodules"))
+		assert.NoError(t, err)
+
+		err = reconcileSubmodules(parentDir, nil, "protobuf-4.x-rc", false, true)
+		assert.NoError(t, err)
+
+		after, err := os.ReadFile(filepath.Join(parentDir, ".gitmodules"))
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+}
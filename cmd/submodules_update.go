@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var submodulesUpdateCmd = &cobra.Command{
+	Use:   "update [path...]",
+	Short: "Advance submodules to the latest commit of their tracked branch",
+	Long: `Advance submodules to the latest commit of their tracked branch.
+
+Each submodule's branch is read from .gitmodules and fetched from origin. The
+new submodule pointers are committed in this repository with a message that
+lists the old and new SHA and the commits pulled in for every submodule.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		message, _ := cmd.Flags().GetString("message")
+		push, _ := cmd.Flags().GetBool("push")
+		if err := updateSubmodules(".", args, message, push); err != nil {
+			fmt.Println("Error updating submodules:", err)
+		}
+	},
+}
+
+func init() {
+	submodulesCmd.AddCommand(submodulesUpdateCmd)
+	submodulesUpdateCmd.Flags().StringP("message", "m", "chore: update submodules", "Subject of the parent commit")
+	submodulesUpdateCmd.Flags().Bool("push", false, "Push the parent repository after committing")
+}
+
+// submoduleUpdate records how far a submodule pointer moved.
+type submoduleUpdate struct {
+	Path string
+	Old  string
+	New  string
+	Log  string
+}
+
+// updateSubmodules moves the submodules of parentDir, or only those in
+// paths, to the tip of their tracked branch and commits the result.
+func updateSubmodules(parentDir string, paths []string, message string, push bool) error {
+	submodules, err := readGitmodules(parentDir)
+	if err != nil {
+		return err
+	}
+
+	selected := paths
+	if len(selected) == 0 {
+		for path := range submodules {
+			selected = append(selected, path)
+		}
+		sort.Strings(selected)
+	}
+
+	var updates []submoduleUpdate
+	for _, path := range selected {
+		sm, ok := submodules[path]
+		if !ok {
+			fmt.Printf("Skipping %s: not a submodule\n", path)
+			continue
+		}
+		fmt.Printf("--- Updating submodule %s ---\n", sm.Path)
+		update, err := updateSubmodule(parentDir, sm)
+		if err != nil {
+			fmt.Printf("Error updating %s: %v\n", sm.Path, err)
+			continue
+		}
+		if update.Old == update.New {
+			fmt.Printf("%s is up to date at %s\n", sm.Path, shortSHA(update.New))
+			continue
+		}
+		fmt.Printf("%s: %s -> %s\n", sm.Path, shortSHA(update.Old), shortSHA(update.New))
+		updates = append(updates, update)
+	}
+
+	if len(updates) == 0 {
+		fmt.Println("All submodules are up to date.")
+		return nil
+	}
+
+	// Commit only the submodule pointers so that unrelated changes already
+	// staged in the parent repository are left alone.
+	args := []string{"commit", "-m", submoduleCommitMessage(message, updates), "--"}
+	for _, u := range updates {
+		args = append(args, u.Path)
+	}
+	if _, err := runGit(parentDir, args...); err != nil {
+		return err
+	}
+	fmt.Printf("Committed %d submodule updates\n", len(updates))
+
+	if push {
+		if _, err := runGit(parentDir, "push", "origin", "HEAD"); err != nil {
+			return err
+		}
+		fmt.Println("Pushed parent repository")
+	}
+	return nil
+}
+
+// updateSubmodule fetches the tracked branch of sm, checks out its tip and
+// stages the new pointer in parentDir.
+func updateSubmodule(parentDir string, sm submodule) (submoduleUpdate, error) {
+	update := submoduleUpdate{Path: sm.Path}
+	if sm.Branch == "" {
+		return update, fmt.Errorf("no branch set in .gitmodules")
+	}
+
+	old, err := runGit(parentDir, "rev-parse", "HEAD:"+sm.Path)
+	if err != nil {
+		return update, err
+	}
+	update.Old = old
+
+	smDir := filepath.Join(parentDir, sm.Path)
+	if !isGitRepo(smDir) {
+		if _, err := runGit(parentDir, "submodule", "update", "--init", "--", sm.Path); err != nil {
+			return update, err
+		}
+	}
+	if dirty, err := isDirty(smDir); err != nil {
+		return update, err
+	} else if dirty {
+		return update, fmt.Errorf("submodule has uncommitted changes")
+	}
+
+	if _, err := runGit(smDir, "fetch", "origin", sm.Branch); err != nil {
+		return update, err
+	}
+	tip, err := runGit(smDir, "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return update, err
+	}
+	update.New = tip
+	if tip == old {
+		return update, nil
+	}
+
+	current, _ := runGit(smDir, "symbolic-ref", "--short", "-q", "HEAD")
+	if current == sm.Branch {
+		_, err = runGit(smDir, "merge", "--ff-only", tip)
+	} else {
+		_, err = runGit(smDir, "checkout", "-q", "--detach", tip)
+	}
+	if err != nil {
+		return update, err
+	}
+
+	if log, err := runGit(smDir, "log", "--oneline", "--no-decorate", old+".."+tip); err == nil {
+		update.Log = log
+	}
+	_, err = runGit(parentDir, "add", "--", sm.Path)
+	return update, err
+}
+
+// submoduleCommitMessage builds the parent commit message: the subject
+// followed by an old -> new line and the commit log of every submodule.
+func submoduleCommitMessage(subject string, updates []submoduleUpdate) string {
+	var b strings.Builder
+	b.WriteString(subject)
+	b.WriteString("\n")
+	for _, u := range updates {
+		fmt.Fprintf(&b, "\n%s: %s -> %s\n", u.Path, shortSHA(u.Old), shortSHA(u.New))
+		if u.Log == "" {
+			b.WriteString("  (commit log unavailable)\n")
+			continue
+		}
+		for _, line := range strings.Split(u.Log, "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// shortSHA abbreviates a commit SHA for display.
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
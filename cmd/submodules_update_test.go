@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupSubmodule creates an upstream repository with a main branch and a
+// parent repository that tracks it as the submodule "lib". It returns the
+// upstream and parent directories.
+func setupSubmodule(t *testing.T) (string, string) {
+	upstreamDir, err := os.MkdirTemp("", "upstream")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(upstreamDir) })
+	setupGitRepo(t, upstreamDir)
+	createCommit(t, upstreamDir, "first upstream commit")
+	runGitT(t, upstreamDir, "branch", "-M", "main")
+
+	parentDir, err := os.MkdirTemp("", "parent")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(parentDir) })
+	setupGitRepo(t, parentDir)
+	runGitT(t, parentDir, "-c", "protocol.file.allow=always", "submodule", "add", "-b", "main", upstreamDir, "lib")
+	runGitT(t, parentDir, "commit", "-m", "add lib")
+	return upstreamDir, parentDir
+}
+
+func TestUpdateSubmodules(t *testing.T) {
+	t.Run("moves pointer and commits log", func(t *testing.T) {
+		upstreamDir, parentDir := setupSubmodule(t)
+		old := runGitT(t, parentDir, "rev-parse", "HEAD:lib")
+
+		createCommit(t, upstreamDir, "second upstream commit")
+		tip := runGitT(t, upstreamDir, "rev-parse", "HEAD")
+
+		err := updateSubmodules(parentDir, nil, "chore: update submodules", false)
+		assert.NoError(t, err)
+
+		assert.Equal(t, tip, runGitT(t, parentDir, "rev-parse", "HEAD:lib"))
+		message := runGitT(t, parentDir, "log", "-1", "--format=%B")
+		assert.Contains(t, message, "chore: update submodules")
+		assert.Contains(t, message, "lib: "+shortSHA(old)+" -> "+shortSHA(tip))
+		assert.Contains(t, message, "second upstream commit")
+	})
+
+	t.Run("leaves other staged changes uncommitted", func(t *testing.T) {
+		upstreamDir, parentDir := setupSubmodule(t)
+		createCommit(t, upstreamDir, "second upstream commit")
+
+		err := os.WriteFile(filepath.Join(parentDir, "notes.txt"), []byte("wip"), 0644)
+		assert.NoError(t, err)
+		runGitT(t, parentDir, "add", "notes.txt")
+
+		err = updateSubmodules(parentDir, nil, "chore: update submodules", false)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "lib", runGitT(t, parentDir, "show", "--name-only", "--format=", "HEAD"))
+		assert.Equal(t, "notes.txt", runGitT(t, parentDir, "diff", "--cached", "--name-only"))
+	})
+
+	t.Run("does nothing when up to date", func(t *testing.T) {
+		_, parentDir := setupSubmodule(t)
+		head := runGitT(t, parentDir, "rev-parse", "HEAD")
+
+		err := updateSubmodules(parentDir, nil, "chore: update submodules", false)
+		assert.NoError(t, err)
+		assert.Equal(t, head, runGitT(t, parentDir, "rev-parse", "HEAD"))
+	})
+
+	t.Run("skips dirty submodules", func(t *testing.T) {
+		upstreamDir, parentDir := setupSubmodule(t)
+		createCommit(t, upstreamDir, "second upstream commit")
+		head := runGitT(t, parentDir, "rev-parse", "HEAD")
+
+		err := os.WriteFile(filepath.Join(parentDir, "lib", "wip.txt"), []byte("wip"), 0644)
+		assert.NoError(t, err)
+
+		err = updateSubmodules(parentDir, nil, "chore: update submodules", false)
+		assert.NoError(t, err)
+		assert.Equal(t, head, runGitT(t, parentDir, "rev-parse", "HEAD"))
+	})
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:     "sync",
+	Aliases: []string{"pull-main"},
+	Short:   "Fetch all remotes and fast-forward branches without changing the checkout",
+	Long: `Fetch all remotes and fast-forward branches without changing the checkout.
+
+Each branch given with --branches is fast-forwarded to origin/<branch>. Without
+--branches the repository's default branch is used, read from origin/HEAD or,
+if that is not set, from the GitHub API. The checked out branch is never
+switched, and branches that have diverged from origin are left alone. Branches
+that are only ahead of origin count as up to date. In a shallow clone that
+lacks the history connecting a branch to origin, the branch is left alone
+and reported separately from diverged branches.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		branches, _ := cmd.Flags().GetStringSlice("branches")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+
+		for _, repo := range repos {
+			fmt.Printf("--- Syncing %s ---\n", repo.Dir())
+			targets := branches
+			if len(targets) == 0 {
+				branch, err := defaultBranch(repo)
+				if err != nil {
+					fmt.Printf("Error finding default branch of %s: %v\n", repo.Dir(), err)
+					continue
+				}
+				targets = []string{branch}
+			}
+			syncRepo(repo.Dir(), targets)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().StringSlice("branches", nil, "Branches to fast-forward (default: the repository's default branch)")
+}
+
+// defaultBranch returns the default branch of repo from origin/HEAD, falling
+// back to the GitHub API.
+func defaultBranch(repo repoEntry) (string, error) {
+	if ref, err := runGit(repo.Dir(), "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+
+	client, err := newGitHubClient()
+	if err != nil {
+		return "", err
+	}
+	r, _, err := client.Repositories.Get(context.Background(), repo.Owner, repo.Name)
+	if err != nil {
+		return "", err
+	}
+	return r.GetDefaultBranch(), nil
+}
+
+// syncRepo fetches all remotes of repoDir and fast-forwards each branch to
+// origin/<branch>.
+func syncRepo(repoDir string, branches []string) {
+	if _, err := runGit(repoDir, "fetch", "--all", "--prune"); err != nil {
+		fmt.Printf("Error fetching in %s: %v\n", repoDir, err)
+		return
+	}
+
+	for _, branch := range branches {
+		if err := fetchBranch(repoDir, branch); err != nil {
+			fmt.Printf("Error fetching %s in %s: %v\n", branch, repoDir, err)
+			continue
+		}
+		if err := fastForward(repoDir, branch); err != nil {
+			fmt.Printf("Not updating %s in %s: %v\n", branch, repoDir, err)
+		}
+	}
+}
+
+// fastForward moves the local branch to origin/<branch> if that is a fast
+// forward. The checked out branch is merged with --ff-only so that the
+// working tree follows; other branches are updated in place.
+func fastForward(repoDir, branch string) error {
+	remote := "origin/" + branch
+	target, err := runGit(repoDir, "rev-parse", remote)
+	if err != nil {
+		return err
+	}
+
+	old, err := runGit(repoDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	if err != nil {
+		if _, err := runGit(repoDir, "branch", "--track", branch, remote); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s at %s\n", branch, shortSHA(target))
+		return nil
+	}
+	if old == target {
+		fmt.Printf("%s is up to date\n", branch)
+		return nil
+	}
+	if _, err := runGit(repoDir, "merge-base", "--is-ancestor", old, target); err != nil {
+		if _, err := runGit(repoDir, "merge-base", "--is-ancestor", target, old); err == nil {
+			fmt.Printf("%s is ahead of %s, nothing to update\n", branch, remote)
+			return nil
+		}
+		// A shallow clone may lack the commits connecting the two, so
+		// they only look diverged.
+		if shallow, err := isShallowRepo(repoDir); err == nil && shallow {
+			return fmt.Errorf("the shallow clone lacks the history to tell whether %s can be fast-forwarded to %s (fetch with --unshallow)", branch, remote)
+		}
+		return fmt.Errorf("%s has diverged from %s, refusing to update", branch, remote)
+	}
+
+	current, _ := runGit(repoDir, "symbolic-ref", "--short", "-q", "HEAD")
+	if current == branch {
+		_, err = runGit(repoDir, "merge", "--ff-only", remote)
+	} else {
+		_, err = runGit(repoDir, "update-ref", "refs/heads/"+branch, target, old)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fast-forwarded %s: %s -> %s\n", branch, shortSHA(old), shortSHA(target))
+	return nil
+}
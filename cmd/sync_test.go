@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncRepo(t *testing.T) {
+	t.Run("fast-forwards without switching branches", func(t *testing.T) {
+		repoDir := setupClone(t)
+		commitFile(t, repoDir, "main.txt", "from main")
+		runGitT(t, repoDir, "push", "origin", "main")
+		tip := runGitT(t, repoDir, "rev-parse", "HEAD")
+		runGitT(t, repoDir, "reset", "--hard", "HEAD~1")
+		runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+
+		syncRepo(repoDir, []string{"main"})
+
+		assert.Equal(t, tip, runGitT(t, repoDir, "rev-parse", "main"))
+		assert.True(t, checkBranch(repoDir, "protobuf-4.x-rc"))
+	})
+
+	t.Run("fast-forwards the checked out branch", func(t *testing.T) {
+		repoDir := setupClone(t)
+		commitFile(t, repoDir, "main.txt", "from main")
+		runGitT(t, repoDir, "push", "origin", "main")
+		tip := runGitT(t, repoDir, "rev-parse", "HEAD")
+		runGitT(t, repoDir, "reset", "--hard", "HEAD~1")
+
+		syncRepo(repoDir, []string{"main"})
+
+		assert.Equal(t, tip, runGitT(t, repoDir, "rev-parse", "HEAD"))
+		assert.Equal(t, "from main", runGitT(t, repoDir, "show", "HEAD:main.txt"))
+	})
+
+	t.Run("refuses diverged branches", func(t *testing.T) {
+		repoDir := setupClone(t)
+		commitFile(t, repoDir, "main.txt", "from main")
+		runGitT(t, repoDir, "push", "origin", "main")
+		runGitT(t, repoDir, "reset", "--hard", "HEAD~1")
+		commitFile(t, repoDir, "local.txt", "local only")
+		local := runGitT(t, repoDir, "rev-parse", "HEAD")
+
+		syncRepo(repoDir, []string{"main"})
+
+		assert.Equal(t, local, runGitT(t, repoDir, "rev-parse", "main"))
+	})
+
+	t.Run("treats branches ahead of origin as up to date", func(t *testing.T) {
+		repoDir := setupClone(t)
+		commitFile(t, repoDir, "local.txt", "local only")
+
+		assert.NoError(t, fastForward(repoDir, "main"))
+	})
+
+	t.Run("reports missing history of shallow clones", func(t *testing.T) {
+		repoDir := setupClone(t)
+		commitFile(t, repoDir, "main.txt", "from main")
+		runGitT(t, repoDir, "push", "origin", "main")
+		origin := runGitT(t, repoDir, "remote", "get-url", "origin")
+		shallowDir := filepath.Join(t.TempDir(), "shallow")
+		assert.NoError(t, exec.Command("git", "clone", "--depth=1", "--branch", "main", "file://"+origin, shallowDir).Run())
+		local := runGitT(t, shallowDir, "rev-parse", "main")
+
+		// Rewrite main on origin below the shallow boundary.
+		runGitT(t, repoDir, "reset", "--hard", "HEAD~1")
+		commitFile(t, repoDir, "other.txt", "rewritten")
+		runGitT(t, repoDir, "push", "--force", "origin", "main")
+
+		assert.NoError(t, fetchBranch(shallowDir, "main"))
+		err := fastForward(shallowDir, "main")
+		assert.ErrorContains(t, err, "shallow clone")
+		assert.Equal(t, local, runGitT(t, shallowDir, "rev-parse", "main"))
+	})
+
+	t.Run("creates missing local branches", func(t *testing.T) {
+		repoDir := setupClone(t)
+
+		syncRepo(repoDir, []string{"protobuf-4.x-rc"})
+
+		assert.Equal(t, runGitT(t, repoDir, "rev-parse", "origin/protobuf-4.x-rc"), runGitT(t, repoDir, "rev-parse", "protobuf-4.x-rc"))
+		assert.True(t, checkBranch(repoDir, "main"))
+	})
+}
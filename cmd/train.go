@@ -0,0 +1,454 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var trainCmd = &cobra.Command{
+	Use:   "train",
+	Short: "Release the repositories in dependency order",
+	Long: `Release the repositories in dependency order.
+
+The repositories are walked in the order printed by "graph". A repository
+is released once its release-please manifest on the release branch holds a
+release candidate version (such as 2.50.0-rc1) that has a tag. Once all of
+a repository's upstream repositories are released, the artifacts it uses
+from them are bumped to the released versions and a pull request is opened
+against the release branch; the repository is then released when a newer
+release candidate is tagged.
+
+Progress is kept in a state file, so the command can be run again (or with
+--wait) to continue where it stopped. Delete the state file to start a new
+train.
+
+Repositories whose releases the train cannot follow, such as monorepos
+without a root package, are marked in the state file for a manual release
+and skipped, together with the repositories that depend on them. The rest
+of the train carries on.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		branch, _ := cmd.Flags().GetString("branch")
+		statePath, _ := cmd.Flags().GetString("state")
+		wait, _ := cmd.Flags().GetDuration("wait")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		graph, err := buildRepoGraph(repos)
+		if err != nil {
+			fmt.Println("Error building dependency graph:", err)
+			os.Exit(1)
+		}
+		order, err := graph.Order()
+		if err != nil {
+			fmt.Println("Error ordering repositories:", err)
+			os.Exit(1)
+		}
+		state, err := loadTrainState(statePath, branch)
+		if err != nil {
+			fmt.Println("Error reading train state:", err)
+			os.Exit(1)
+		}
+
+		deadline := time.Now().Add(timeout)
+		for {
+			done, err := runTrain(graph, order, state, statePath)
+			if err != nil {
+				fmt.Println("Error running train:", err)
+				os.Exit(1)
+			}
+			if done {
+				if manual := state.manual(); len(manual) > 0 {
+					fmt.Printf("The train cannot release %s, release them manually along with the repositories that depend on them\n", strings.Join(manual, ", "))
+					os.Exit(1)
+				}
+				fmt.Println("All repositories are released")
+				return
+			}
+			if wait <= 0 {
+				fmt.Println("Run again to continue the train")
+				return
+			}
+			if timeout > 0 && time.Now().Add(wait).After(deadline) {
+				fmt.Println("Timed out waiting for releases, run again to continue the train")
+				os.Exit(1)
+			}
+			fmt.Printf("Waiting %s before checking again\n", wait)
+			time.Sleep(wait)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trainCmd)
+	trainCmd.Flags().String("branch", "protobuf-4.x-rc", "The release branch")
+	trainCmd.Flags().String("state", "train-state.json", "The file that records the progress of the train")
+	trainCmd.Flags().Duration("wait", 0, "Poll for releases at this interval instead of stopping (e.g. 5m)")
+	trainCmd.Flags().Duration("timeout", 6*time.Hour, "Give up waiting after this long")
+}
+
+// errTrainUnsupported marks repositories whose releases the train cannot
+// follow, such as monorepos without a root package. Such repositories are
+// marked for a manual release instead of waiting for a release that is
+// never recognized, and the repositories that depend on them are held.
+var errTrainUnsupported = errors.New("not supported by the train")
+
+// trainState is the progress of a release train, saved between runs.
+type trainState struct {
+	Branch string                     `json:"branch"`
+	Repos  map[string]*trainRepoState `json:"repos"`
+}
+
+// trainRepoState is the progress of one repository in the train.
+type trainRepoState struct {
+	// PR is the URL of the pull request bumping the upstream versions, or
+	// "none" if no bump was needed.
+	PR string `json:"pr,omitempty"`
+	// BaseVersion is the manifest version when the pull request was opened.
+	// Only a newer release candidate counts as the repository's release.
+	BaseVersion string `json:"baseVersion,omitempty"`
+	// Tag and Version identify the released release candidate.
+	Tag     string `json:"tag,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Artifacts maps groupId:artifactId to the released version.
+	Artifacts map[string]string `json:"artifacts,omitempty"`
+	// Manual is the reason the train cannot follow the repository's
+	// releases, if any. The repository has to be released manually.
+	Manual string `json:"manual,omitempty"`
+}
+
+// Released reports whether the repository has released its release
+// candidate.
+func (s *trainRepoState) Released() bool {
+	return s.Tag != ""
+}
+
+func loadTrainState(path, branch string) (*trainState, error) {
+	state := &trainState{Branch: branch, Repos: make(map[string]*trainRepoState)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if state.Branch != branch {
+		return nil, fmt.Errorf("%s is for branch %s, not %s", path, state.Branch, branch)
+	}
+	if state.Repos == nil {
+		state.Repos = make(map[string]*trainRepoState)
+	}
+	return state, nil
+}
+
+func (s *trainState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// manual returns the repositories marked for a manual release, sorted.
+func (s *trainState) manual() []string {
+	var names []string
+	for name, st := range s.Repos {
+		if st.Manual != "" && !st.Released() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// repo returns the state of a repository, creating it if needed.
+func (s *trainState) repo(name string) *trainRepoState {
+	if s.Repos[name] == nil {
+		s.Repos[name] = &trainRepoState{}
+	}
+	return s.Repos[name]
+}
+
+// runTrain makes one pass over the repositories in order, opening bump pull
+// requests and recording releases where possible. The state is saved after
+// every step. It reports whether nothing is left for the train to do: every
+// repository is released, marked for a manual release, or held by one that
+// is. Unsupported repositories are marked for a manual release. Other
+// errors in a single repository are reported and retried on the next pass,
+// except for failures to save the state, which are returned.
+func runTrain(graph *repoGraph, order []repoEntry, state *trainState, statePath string) (bool, error) {
+	done := true
+	// held holds the repositories that wait for a manual release, directly
+	// or through an upstream repository.
+	held := make(map[string]bool)
+	markManual := func(name string, st *trainRepoState, err error) error {
+		fmt.Printf("%s: %v, release it manually\n", name, err)
+		st.Manual = err.Error()
+		held[name] = true
+		if err := state.save(statePath); err != nil {
+			return fmt.Errorf("error saving train state: %w", err)
+		}
+		return nil
+	}
+	for _, repo := range order {
+		name := repo.FullName()
+		st := state.repo(name)
+		if st.Released() {
+			continue
+		}
+		if st.Manual != "" {
+			fmt.Printf("%s: skipped, release it manually (%s)\n", name, st.Manual)
+			held[name] = true
+			continue
+		}
+
+		var waiting, holding []string
+		for _, dep := range graph.Deps[name] {
+			if !state.repo(dep).Released() {
+				waiting = append(waiting, dep)
+			}
+			if held[dep] {
+				holding = append(holding, dep)
+			}
+		}
+		if len(holding) > 0 {
+			fmt.Printf("%s: held until %s is released manually\n", name, strings.Join(holding, ", "))
+			held[name] = true
+			continue
+		}
+		done = false
+		if len(waiting) > 0 {
+			fmt.Printf("%s: waiting for %s\n", name, strings.Join(waiting, ", "))
+			continue
+		}
+
+		if len(graph.Deps[name]) > 0 && st.PR == "" {
+			if err := trainBump(repo, state.Branch, graph.Deps[name], state, st); err != nil {
+				if errors.Is(err, errTrainUnsupported) {
+					if err := markManual(name, st, err); err != nil {
+						return false, err
+					}
+					continue
+				}
+				fmt.Printf("%s: error bumping dependencies: %v\n", name, err)
+				continue
+			}
+			if err := state.save(statePath); err != nil {
+				return false, fmt.Errorf("error saving train state: %w", err)
+			}
+		}
+
+		released, err := trainRelease(repo.Dir(), state.Branch, st)
+		if err != nil {
+			if errors.Is(err, errTrainUnsupported) {
+				if err := markManual(name, st, err); err != nil {
+					return false, err
+				}
+				continue
+			}
+			fmt.Printf("%s: error checking release: %v\n", name, err)
+			continue
+		}
+		if !released {
+			if st.PR != "" && st.PR != "none" {
+				fmt.Printf("%s: waiting for %s to be merged and released\n", name, st.PR)
+			} else {
+				fmt.Printf("%s: waiting for a release candidate to be tagged\n", name)
+			}
+			continue
+		}
+		fmt.Printf("%s: released %s\n", name, st.Tag)
+		if err := state.save(statePath); err != nil {
+			return false, fmt.Errorf("error saving train state: %w", err)
+		}
+	}
+	return done, nil
+}
+
+// trainBump updates the artifacts repo uses from its upstream repositories
+// to their released versions on a new branch and opens a pull request
+// against the release branch. st records the pull request.
+func trainBump(repo repoEntry, branch string, upstreams []string, state *trainState, st *trainRepoState) error {
+	repoDir := repo.Dir()
+	if err := switchBranch(repoDir, branch, false); err != nil {
+		return err
+	}
+	if err := fastForward(repoDir, branch); err != nil {
+		return err
+	}
+	base, err := manifestVersion(repoDir)
+	if err != nil {
+		return err
+	}
+	if base == "" {
+		return fmt.Errorf("no root package version in the release-please manifest: %w", errTrainUnsupported)
+	}
+
+	var releases []string
+	for _, upstream := range upstreams {
+		up := state.repo(upstream)
+		releases = append(releases, fmt.Sprintf("%s %s", upstream, up.Version))
+		keys := make([]string, 0, len(up.Artifacts))
+		for key := range up.Artifacts {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			groupID, artifactID, _ := strings.Cut(key, ":")
+			rewrite := dependencyRewrite{GroupID: groupID, ArtifactID: artifactID, Version: up.Artifacts[key]}
+			if _, err := setRepoDependency(repoDir, rewrite, false); err != nil {
+				runGit(repoDir, "checkout", "--", ".")
+				return err
+			}
+		}
+	}
+
+	dirty, err := isDirty(repoDir)
+	if err != nil {
+		return err
+	}
+	if !dirty {
+		fmt.Printf("%s: already uses %s\n", repo.FullName(), strings.Join(releases, ", "))
+		st.PR = "none"
+		st.BaseVersion = ""
+		return nil
+	}
+
+	title := "deps: update to " + strings.Join(releases, ", ")
+	work := prBranchFromMessage(title)
+	if _, err := runGit(repoDir, "checkout", "-B", work); err != nil {
+		return err
+	}
+	body := "Updates the dependencies on upstream release candidates:\n\n"
+	for _, r := range releases {
+		body += "- " + r + "\n"
+	}
+	url, err := pushTrainBump(repoDir, branch, work, title, body)
+	// Go back to the release branch whether or not the pull request was
+	// opened, so that the next run starts from it.
+	if _, checkoutErr := runGit(repoDir, "checkout", "--force", branch); err == nil {
+		err = checkoutErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: opened %s\n", repo.FullName(), url)
+	st.PR = url
+	st.BaseVersion = base
+	return nil
+}
+
+// pushTrainBump commits the bump on the checked out work branch, pushes it
+// and opens a pull request against branch.
+func pushTrainBump(repoDir, branch, work, title, body string) (string, error) {
+	if _, err := runGit(repoDir, "commit", "--all", "--message", title); err != nil {
+		return "", err
+	}
+	if _, err := runGit(repoDir, "push", "--force-with-lease", "origin", "HEAD:refs/heads/"+work); err != nil {
+		return "", err
+	}
+	return openPullRequest(repoDir, branch, work, title, body)
+}
+
+// trainRelease checks whether the release branch of repoDir holds a tagged
+// release candidate newer than st.BaseVersion, and records it in st.
+func trainRelease(repoDir, branch string, st *trainRepoState) (bool, error) {
+	if err := fetchBranch(repoDir, branch); err != nil {
+		return false, err
+	}
+	files := locateReleasePleaseFiles(repoDir)
+	if files.Manifest == "" {
+		return false, fmt.Errorf("no release-please manifest found")
+	}
+	data, err := runGit(repoDir, "show", "origin/"+branch+":"+files.Manifest)
+	if err != nil {
+		return false, err
+	}
+	manifest, err := parseManifestVersion([]byte(data))
+	if err != nil {
+		return false, err
+	}
+	if manifest == "" {
+		return false, fmt.Errorf("%s has several packages and no root package: %w", files.Manifest, errTrainUnsupported)
+	}
+	if manifest == st.BaseVersion {
+		return false, nil
+	}
+	v, err := parseVersion(manifest)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := v.rcNumber(); !ok {
+		return false, nil
+	}
+
+	tag, err := remoteReleaseTag(repoDir, v)
+	if err != nil || tag == "" {
+		return false, err
+	}
+	if _, err := runGit(repoDir, "fetch", "--depth=1", "origin", "refs/tags/"+tag+":refs/tags/"+tag); err != nil {
+		return false, err
+	}
+	poms, err := readPOMsAtRef(repoDir, tag)
+	if err != nil {
+		return false, err
+	}
+
+	st.Tag = tag
+	st.Version = v.String()
+	st.Artifacts = make(map[string]string)
+	for _, pom := range poms {
+		st.Artifacts[pom.Key()] = pom.resolve(pom.EffectiveVersion())
+	}
+	return true, nil
+}
+
+// remoteReleaseTag returns the tag on origin for version v, or "" if there
+// is none. A tag without a component prefix, such as v2.50.0-rc1, is
+// preferred; otherwise a single tag with a component prefix, such as
+// google-cloud-storage-v2.50.0-rc1, is accepted. Several prefixed tags for
+// the same version are an error, since the train cannot tell which package
+// of a monorepo the root package is.
+func remoteReleaseTag(repoDir string, v version) (string, error) {
+	output, err := runGit(repoDir, "ls-remote", "--tags", "--refs", "origin")
+	if err != nil {
+		return "", err
+	}
+	var prefixed []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		tag := strings.TrimPrefix(fields[1], "refs/tags/")
+		component, tv, err := parseTag(tag)
+		if err != nil || tv != v {
+			continue
+		}
+		if component == "" {
+			return tag, nil
+		}
+		prefixed = append(prefixed, tag)
+	}
+	switch len(prefixed) {
+	case 0:
+		return "", nil
+	case 1:
+		return prefixed[0], nil
+	default:
+		return "", fmt.Errorf("several tags for %s: %s: %w", v, strings.Join(prefixed, ", "), errTrainUnsupported)
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupReleasedClone returns a clone whose release branch has a manifest at
+// 2.50.0-rc1 and a POM for com.google.api:gax.
+func setupReleasedClone(t *testing.T) string {
+	repoDir := setupClone(t)
+	runGitT(t, repoDir, "checkout", "protobuf-4.x-rc")
+	commitFile(t, repoDir, ".release-please-manifest.json", "{\n  \".\": \"2.50.0-rc1\"\n}\n")
+	writePOM(t, filepath.Join(repoDir, "pom.xml"), "com.google.api", "gax", "${gax.version}", `  <properties>
+    <gax.version>2.53.0-rc1</gax.version>
+  </properties>`)
+	runGitT(t, repoDir, "add", "pom.xml")
+	runGitT(t, repoDir, "commit", "-m", "release")
+	runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+	return repoDir
+}
+
+func TestTrainRelease(t *testing.T) {
+	t.Run("waits for the tag", func(t *testing.T) {
+		repoDir := setupReleasedClone(t)
+		st := &trainRepoState{}
+		released, err := trainRelease(repoDir, "protobuf-4.x-rc", st)
+		assert.NoError(t, err)
+		assert.False(t, released)
+	})
+
+	t.Run("records tagged release candidate", func(t *testing.T) {
+		repoDir := setupReleasedClone(t)
+		runGitT(t, repoDir, "tag", "v2.50.0-rc1")
+		runGitT(t, repoDir, "push", "origin", "v2.50.0-rc1")
+		runGitT(t, repoDir, "tag", "--delete", "v2.50.0-rc1")
+
+		st := &trainRepoState{}
+		released, err := trainRelease(repoDir, "protobuf-4.x-rc", st)
+		assert.NoError(t, err)
+		assert.True(t, released)
+		assert.Equal(t, "v2.50.0-rc1", st.Tag)
+		assert.Equal(t, map[string]string{"com.google.api:gax": "2.53.0-rc1"}, st.Artifacts)
+	})
+
+	t.Run("ignores samples and unparsable POMs", func(t *testing.T) {
+		repoDir := setupReleasedClone(t)
+		for _, dir := range []string{"samples", "template"} {
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, dir), 0755))
+		}
+		writePOM(t, filepath.Join(repoDir, "samples", "pom.xml"), "com.example", "gax-samples", "1.0.0", "")
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "template", "pom.xml"), []byte("<project><groupId>${groupId}"), 0644))
+		runGitT(t, repoDir, "add", "samples", "template")
+		runGitT(t, repoDir, "commit", "-m", "samples")
+		runGitT(t, repoDir, "tag", "v2.50.0-rc1")
+		runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc", "v2.50.0-rc1")
+
+		st := &trainRepoState{}
+		released, err := trainRelease(repoDir, "protobuf-4.x-rc", st)
+		assert.NoError(t, err)
+		assert.True(t, released)
+		assert.Equal(t, map[string]string{"com.google.api:gax": "2.53.0-rc1"}, st.Artifacts)
+	})
+
+	t.Run("requires a release newer than the base version", func(t *testing.T) {
+		repoDir := setupReleasedClone(t)
+		runGitT(t, repoDir, "tag", "v2.50.0-rc1")
+		runGitT(t, repoDir, "push", "origin", "v2.50.0-rc1")
+
+		st := &trainRepoState{BaseVersion: "2.50.0-rc1"}
+		released, err := trainRelease(repoDir, "protobuf-4.x-rc", st)
+		assert.NoError(t, err)
+		assert.False(t, released)
+	})
+}
+
+func TestTrainReleaseTags(t *testing.T) {
+	t.Run("accepts a single component tag", func(t *testing.T) {
+		repoDir := setupReleasedClone(t)
+		runGitT(t, repoDir, "tag", "gax-v2.50.0-rc1")
+		runGitT(t, repoDir, "push", "origin", "gax-v2.50.0-rc1")
+
+		st := &trainRepoState{}
+		released, err := trainRelease(repoDir, "protobuf-4.x-rc", st)
+		assert.NoError(t, err)
+		assert.True(t, released)
+		assert.Equal(t, "gax-v2.50.0-rc1", st.Tag)
+	})
+
+	t.Run("rejects ambiguous component tags", func(t *testing.T) {
+		repoDir := setupReleasedClone(t)
+		runGitT(t, repoDir, "tag", "gax-v2.50.0-rc1")
+		runGitT(t, repoDir, "tag", "grpc-gcp-v2.50.0-rc1")
+		runGitT(t, repoDir, "push", "origin", "--tags")
+
+		_, err := trainRelease(repoDir, "protobuf-4.x-rc", &trainRepoState{})
+		assert.ErrorIs(t, err, errTrainUnsupported)
+	})
+
+	t.Run("rejects manifests without a root package", func(t *testing.T) {
+		repoDir := setupReleasedClone(t)
+		commitFile(t, repoDir, ".release-please-manifest.json", "{\n  \"gax\": \"2.50.0-rc1\",\n  \"grpc-gcp\": \"1.6.0-rc1\"\n}\n")
+		runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+
+		_, err := trainRelease(repoDir, "protobuf-4.x-rc", &trainRepoState{})
+		assert.ErrorIs(t, err, errTrainUnsupported)
+	})
+}
+
+func TestRunTrainManual(t *testing.T) {
+	repoDir := setupReleasedClone(t)
+	commitFile(t, repoDir, ".release-please-manifest.json", "{\n  \"gax\": \"2.50.0-rc1\",\n  \"grpc-gcp\": \"1.6.0-rc1\"\n}\n")
+	runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+	t.Chdir(filepath.Dir(repoDir))
+
+	mono := repoEntry{Owner: "googleapis", Name: filepath.Base(repoDir)}
+	storage := repoEntry{Owner: "googleapis", Name: "java-storage"}
+	graph := &repoGraph{
+		Repos: []repoEntry{mono, storage},
+		Deps:  map[string][]string{storage.FullName(): {mono.FullName()}},
+	}
+	statePath := filepath.Join(t.TempDir(), "train-state.json")
+	state := &trainState{Branch: "protobuf-4.x-rc", Repos: make(map[string]*trainRepoState)}
+
+	_, err := runTrain(graph, []repoEntry{mono, storage}, state, statePath)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, state.repo(mono.FullName()).Manual)
+	assert.Empty(t, state.repo(storage.FullName()).PR)
+	assert.Equal(t, []string{mono.FullName()}, state.manual())
+
+	// The next pass has nothing left to do.
+	state, err = loadTrainState(statePath, "protobuf-4.x-rc")
+	assert.NoError(t, err)
+	done, err := runTrain(graph, []repoEntry{mono, storage}, state, statePath)
+	assert.NoError(t, err)
+	assert.True(t, done)
+}
+
+func TestTrainBump(t *testing.T) {
+	repoDir := setupClone(t)
+	runGitT(t, repoDir, "checkout", "protobuf-4.x-rc")
+	commitFile(t, repoDir, ".release-please-manifest.json", "{\n  \".\": \"2.43.0\"\n}\n")
+	writePOM(t, filepath.Join(repoDir, "pom.xml"), "com.google.cloud", "google-cloud-storage", "2.43.0", `  <parent>
+    <groupId>com.google.cloud</groupId>
+    <artifactId>google-cloud-shared-config</artifactId>
+    <version>1.11.0</version>
+  </parent>`)
+	runGitT(t, repoDir, "add", "pom.xml")
+	runGitT(t, repoDir, "commit", "-m", "add pom")
+	runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+
+	// gh fails, so the pull request cannot be opened.
+	binDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(binDir, "gh"), []byte("#!/bin/sh\nexit 1\n"), 0755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Chdir(filepath.Dir(repoDir))
+
+	state := &trainState{Branch: "protobuf-4.x-rc", Repos: map[string]*trainRepoState{
+		"googleapis/java-shared-config": {
+			Tag:       "v1.12.0-rc1",
+			Version:   "1.12.0-rc1",
+			Artifacts: map[string]string{"com.google.cloud:google-cloud-shared-config": "1.12.0-rc1"},
+		},
+	}}
+	st := state.repo("googleapis/java-storage")
+	repo := repoEntry{Owner: "googleapis", Name: filepath.Base(repoDir)}
+	err := trainBump(repo, "protobuf-4.x-rc", []string{"googleapis/java-shared-config"}, state, st)
+	assert.Error(t, err)
+	assert.Empty(t, st.PR)
+
+	assert.Equal(t, "protobuf-4.x-rc", runGitT(t, repoDir, "symbolic-ref", "--short", "HEAD"))
+	work := prBranchFromMessage("deps: update to googleapis/java-shared-config 1.12.0-rc1")
+	assert.Contains(t, runGitT(t, repoDir, "show", "origin/"+work+":pom.xml"), "<version>1.12.0-rc1</version>")
+}
+
+func TestTrainState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "train-state.json")
+
+	state, err := loadTrainState(path, "protobuf-4.x-rc")
+	assert.NoError(t, err)
+	state.repo("googleapis/sdk-platform-java").Tag = "v2.50.0-rc1"
+	assert.NoError(t, state.save(path))
+
+	state, err = loadTrainState(path, "protobuf-4.x-rc")
+	assert.NoError(t, err)
+	assert.True(t, state.repo("googleapis/sdk-platform-java").Released())
+	assert.False(t, state.repo("googleapis/java-storage").Released())
+
+	_, err = loadTrainState(path, "main")
+	assert.Error(t, err)
+}
@@ -1,9 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os/exec"
-	"path/filepath"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -11,27 +12,73 @@ import (
 var updateBranchCmd = &cobra.Command{
 	Use:   "update-branch",
 	Short: "Update a branch with the latest from main",
+	Long: `Update a branch with the latest from main.
+
+The branch is updated with the merge, rebase or squash strategy. If the update
+conflicts it is aborted, the repository is left clean on the branch, and the
+conflicting files are listed in the final report. Conflicts in files such as
+versions.txt or CHANGELOG.md are first resolved with the rules from
+--resolve-rules. With --pr the result is pushed to a new branch and a pull
+request is opened with the gh CLI instead of pushing to the branch directly.
+
+A squash does not record the source branch as merged, so a squash that would
+not change the branch is reported as up to date instead of committed.
+
+The local branch is fast-forwarded to origin before it is updated, and the
+branch itself is never force-pushed, so the rebase strategy requires --pr.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		repo, _ := cmd.Flags().GetString("repo")
 		all, _ := cmd.Flags().GetBool("all")
 		branch, _ := cmd.Flags().GetString("branch")
 		from, _ := cmd.Flags().GetString("from")
+		strategy, _ := cmd.Flags().GetString("strategy")
+		pr, _ := cmd.Flags().GetBool("pr")
+		rulesFile, _ := cmd.Flags().GetString("resolve-rules")
+		noResolve, _ := cmd.Flags().GetBool("no-resolve")
+		resume, _ := cmd.Flags().GetString("resume")
 
+		switch strategy {
+		case "merge", "rebase", "squash":
+		default:
+			fmt.Printf("Unknown strategy %q, expected merge, rebase or squash\n", strategy)
+			return
+		}
+		if strategy == "rebase" && !pr {
+			fmt.Println("The rebase strategy rewrites the branch and can only be used with --pr")
+			return
+		}
+		opts := updateBranchOptions{Strategy: strategy, PR: pr}
+		if !noResolve {
+			rules, err := loadResolutionRules(rulesFile)
+			if err != nil {
+				fmt.Println("Error reading conflict resolution rules:", err)
+				return
+			}
+			opts.Rules = rules
+		}
+
+		var results []updateResult
 		if repo != "" {
-			updateBranch(repo, branch, from)
+			results = append(results, updateBranch(repo, branch, from, opts))
 		} else if all {
-			repos, err := readLines("github_repositories.txt")
+			repos, err := readRepos("github_repositories.txt")
 			if err != nil {
 				fmt.Println("Error reading repositories file:", err)
 				return
 			}
+			opts.Journal, err = startRun("update-branch", resume)
+			if err != nil {
+				fmt.Println("Error starting run:", err)
+				return
+			}
 			for _, r := range repos {
-				repoDir := filepath.Base(r)
-				updateBranch(repoDir, branch, from)
+				results = append(results, updateBranch(r.Dir(), r.BranchOr(branch), from, opts))
 			}
 		} else {
 			fmt.Println("Please specify either a single repo with --repo or all repos with --all")
+			return
 		}
+		printUpdateReport(results)
 	},
 }
 
@@ -41,42 +88,256 @@ func init() {
 	updateBranchCmd.Flags().BoolP("all", "a", false, "Update all repositories")
 	updateBranchCmd.Flags().StringP("branch", "b", "protobuf-4.x-rc", "The branch to update")
 	updateBranchCmd.Flags().StringP("from", "f", "main", "The branch to merge from")
+	updateBranchCmd.Flags().StringP("strategy", "s", "merge", "How to update the branch: merge, rebase or squash")
+	updateBranchCmd.Flags().Bool("pr", false, "Open a pull request instead of pushing to the branch")
+	updateBranchCmd.Flags().String("resolve-rules", "", "YAML file of conflict resolution rules (default conflict-rules.yaml if present, else built-in rules)")
+	updateBranchCmd.Flags().Bool("no-resolve", false, "Do not resolve any conflicts automatically")
+	updateBranchCmd.Flags().String("resume", "", "Resume an interrupted --all run, skipping completed steps")
 }
 
-func updateBranch(repoDir, branch, from string) {
-	fmt.Printf("---" + " Updating branch '%s' in %s ---" + "\n", branch, repoDir)
+// loadResolutionRules reads rules from filename. Without a file name,
+// conflict-rules.yaml is used if it exists and the built-in rules otherwise.
+func loadResolutionRules(filename string) ([]resolutionRule, error) {
+	if filename == "" {
+		if _, err := os.Stat("conflict-rules.yaml"); err != nil {
+			return defaultResolutionRules, nil
+		}
+		filename = "conflict-rules.yaml"
+	}
+	return readResolutionRules(filename)
+}
 
-	// Fetch
-	fetchCmd := exec.Command("git", "fetch", "origin")
-	fetchCmd.Dir = repoDir
-	if output, err := fetchCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error fetching in %s: %s\n%s", repoDir, err, output)
-		return
+// updateBranchOptions controls how updateBranch brings in the changes.
+type updateBranchOptions struct {
+	// Strategy is one of merge, rebase or squash.
+	Strategy string
+	// PR pushes to a new branch and opens a pull request.
+	PR bool
+	// Rules resolve conflicts in known files before giving up.
+	Rules []resolutionRule
+	// Journal records completed steps so an interrupted run can resume.
+	Journal *runJournal
+}
+
+// updateResult is the outcome of updating a single repository.
+type updateResult struct {
+	Repo      string
+	Updated   bool
+	Conflicts []string
+	PR        string
+	Err       error
+}
+
+// prBranchName is the branch used to propose merging from into branch.
+func prBranchName(branch, from string) string {
+	return fmt.Sprintf("chore/merge-%s-into-%s", from, branch)
+}
+
+func updateBranch(repoDir, branch, from string, opts updateBranchOptions) updateResult {
+	fmt.Printf("---"+" Updating branch '%s' in %s ---"+"\n", branch, repoDir)
+	result := updateResult{Repo: repoDir}
+	journal := opts.Journal
+	if journal.Done(repoDir, stepDone) {
+		fmt.Printf("Skipping %s: completed in run %s\n", repoDir, journal.ID)
+		return result
 	}
 
-	// Checkout
-	checkoutCmd := exec.Command("git", "checkout", branch)
-	checkoutCmd.Dir = repoDir
-	if output, err := checkoutCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error checking out branch in %s: %s\n%s", repoDir, err, output)
-		return
+	workBranch := branch
+	if opts.PR {
+		workBranch = prBranchName(branch, from)
 	}
 
-	// Merge
-	mergeCmd := exec.Command("git", "merge", "origin/"+from)
-	mergeCmd.Dir = repoDir
-	if output, err := mergeCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error merging in %s: %s\n%s", repoDir, err, output)
-		return
+	// The update is skipped when resuming a run in which it succeeded but
+	// the push or pull request did not.
+	if !journal.Done(repoDir, "update") {
+		dirty, err := isDirty(repoDir)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if dirty {
+			result.Err = fmt.Errorf("working tree has uncommitted changes")
+			return result
+		}
+
+		// Fetch and checkout
+		if err := switchBranch(repoDir, branch, false); err != nil {
+			result.Err = err
+			return result
+		}
+		if err := fastForward(repoDir, branch); err != nil {
+			result.Err = err
+			return result
+		}
+		if err := fetchBranch(repoDir, from); err != nil {
+			result.Err = err
+			return result
+		}
+
+		_, behind, err := aheadBehind(repoDir, "origin/"+from)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if behind == 0 {
+			fmt.Printf("Branch '%s' in %s already contains %s\n", branch, repoDir, from)
+			journal.Complete(repoDir, stepDone)
+			return result
+		}
+
+		if opts.PR {
+			if _, err := runGit(repoDir, "checkout", "-B", workBranch); err != nil {
+				result.Err = err
+				return result
+			}
+		}
+
+		// Update
+		conflicts, err := applyUpdate(repoDir, branch, from, opts)
+		if errors.Is(err, errAlreadyApplied) {
+			fmt.Printf("Branch '%s' in %s already has the changes from %s\n", branch, repoDir, from)
+			if opts.PR {
+				runGit(repoDir, "checkout", branch)
+			}
+			journal.Complete(repoDir, stepDone)
+			return result
+		}
+		if err != nil || len(conflicts) > 0 {
+			result.Conflicts = conflicts
+			result.Err = err
+			if opts.PR {
+				runGit(repoDir, "checkout", branch)
+			}
+			return result
+		}
+		journal.Complete(repoDir, "update")
 	}
 
 	// Push
-	pushCmd := exec.Command("git", "push", "origin", branch)
-	pushCmd.Dir = repoDir
-	if output, err := pushCmd.CombinedOutput(); err != nil {
-		fmt.Printf("Error pushing in %s: %s\n%s", repoDir, err, output)
-		return
+	err := journal.Step(repoDir, "push", func() error {
+		// Only the pull request branch, which this command owns, may be
+		// rewritten.
+		pushArgs := []string{"push", "origin", workBranch}
+		if opts.PR {
+			pushArgs = []string{"push", "--force-with-lease", "origin", workBranch}
+		}
+		_, err := runGit(repoDir, pushArgs...)
+		return err
+	})
+	if err != nil {
+		result.Err = err
+		return result
 	}
 
+	if opts.PR {
+		err := journal.Step(repoDir, "pull-request", func() error {
+			title := fmt.Sprintf("chore: merge %s into %s", from, branch)
+			body := fmt.Sprintf("Brings the latest changes from `%s` into `%s` using the %s strategy.", from, branch, opts.Strategy)
+			url, err := openPullRequest(repoDir, branch, workBranch, title, body)
+			if err != nil {
+				return err
+			}
+			result.PR = url
+			fmt.Printf("Opened pull request for '%s' in %s: %s\n", branch, repoDir, url)
+			return nil
+		})
+		runGit(repoDir, "checkout", branch)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	result.Updated = true
+	journal.Complete(repoDir, stepDone)
 	fmt.Printf("Successfully updated branch '%s' in %s\n", branch, repoDir)
+	return result
+}
+
+// applyUpdate brings origin/<from> into the checked out branch with the
+// given strategy. Conflicts matching opts.Rules are resolved automatically.
+// If other conflicts remain the operation is aborted, leaving the working
+// tree clean, and the conflicting files are returned.
+func applyUpdate(repoDir, branch, from string, opts updateBranchOptions) ([]string, error) {
+	var args, abort, resume []string
+	switch opts.Strategy {
+	case "merge":
+		args = []string{"merge", "--no-edit", "origin/" + from}
+		abort = []string{"merge", "--abort"}
+		resume = []string{"commit", "--no-edit"}
+	case "rebase":
+		args = []string{"rebase", "origin/" + from}
+		abort = []string{"rebase", "--abort"}
+		resume = []string{"-c", "core.editor=true", "rebase", "--continue"}
+	case "squash":
+		args = []string{"merge", "--squash", "origin/" + from}
+		abort = []string{"reset", "--hard", "HEAD"}
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", opts.Strategy)
+	}
+
+	_, err := runGit(repoDir, args...)
+	for err != nil {
+		conflicts, conflictErr := conflictedFiles(repoDir)
+		if conflictErr != nil || len(conflicts) == 0 {
+			return nil, abortUpdate(repoDir, abort, err)
+		}
+		if remaining := resolveConflicts(repoDir, conflicts, opts.Rules, opts.Strategy == "rebase"); len(remaining) > 0 {
+			fmt.Printf("Conflicts updating %s, aborted: %s\n", repoDir, strings.Join(remaining, ", "))
+			return remaining, abortUpdate(repoDir, abort, nil)
+		}
+		if resume == nil {
+			break
+		}
+		// All conflicts were resolved, carry on with the merge or rebase.
+		_, err = runGit(repoDir, resume...)
+	}
+
+	if opts.Strategy == "squash" {
+		// Earlier squashes leave from unmerged, so the same range is
+		// squashed again on every run and may have nothing left to add.
+		if _, err := runGit(repoDir, "diff", "--cached", "--quiet"); err == nil {
+			return nil, errAlreadyApplied
+		}
+		message := fmt.Sprintf("chore: merge %s into %s", from, branch)
+		if _, err := runGit(repoDir, "commit", "-m", message); err != nil {
+			runGit(repoDir, "reset", "--hard", "HEAD")
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// errAlreadyApplied is returned by applyUpdate when a squash would not
+// change the branch.
+var errAlreadyApplied = errors.New("changes are already applied")
+
+// abortUpdate runs the abort command of an update strategy and returns err,
+// or the abort failure if there is one.
+func abortUpdate(repoDir string, abort []string, err error) error {
+	if _, abortErr := runGit(repoDir, abort...); abortErr != nil {
+		if err == nil {
+			return abortErr
+		}
+		return fmt.Errorf("%v; additionally failed to abort: %v", err, abortErr)
+	}
+	return err
+}
+
+func printUpdateReport(results []updateResult) {
+	fmt.Println("--- Update report ---")
+	for _, r := range results {
+		switch {
+		case len(r.Conflicts) > 0:
+			fmt.Printf("%s: conflicts in %s\n", r.Repo, strings.Join(r.Conflicts, ", "))
+		case r.Err != nil:
+			fmt.Printf("%s: error: %s\n", r.Repo, strings.SplitN(r.Err.Error(), "\n", 2)[0])
+		case r.PR != "":
+			fmt.Printf("%s: pull request %s\n", r.Repo, r.PR)
+		case r.Updated:
+			fmt.Printf("%s: updated\n", r.Repo)
+		default:
+			fmt.Printf("%s: already up to date\n", r.Repo)
+		}
+	}
 }
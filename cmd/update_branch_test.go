@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -26,3 +28,112 @@ func TestUpdateBranchCmd(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, all)
 }
+
+// commitFile writes content to name in dir and commits it.
+func commitFile(t *testing.T, dir, name, content string) {
+	err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	assert.NoError(t, err)
+	runGitT(t, dir, "add", name)
+	runGitT(t, dir, "commit", "-m", "update "+name)
+}
+
+func TestUpdateBranch(t *testing.T) {
+	for _, strategy := range []string{"merge", "rebase", "squash"} {
+		// A rebased branch is only pushed to a pull request branch.
+		if strategy != "rebase" {
+			t.Run(strategy+" pushes the updated branch", func(t *testing.T) {
+				repoDir := setupClone(t)
+				commitFile(t, repoDir, "main.txt", "from main")
+				runGitT(t, repoDir, "push", "origin", "main")
+
+				result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: strategy})
+				assert.NoError(t, result.Err)
+				assert.True(t, result.Updated)
+				assert.Empty(t, result.Conflicts)
+				assert.Equal(t, "from main", runGitT(t, repoDir, "show", "origin/protobuf-4.x-rc:main.txt"))
+			})
+		}
+
+		t.Run(strategy+" aborts on conflict", func(t *testing.T) {
+			repoDir := setupClone(t)
+			commitFile(t, repoDir, "test.txt", "from main")
+			runGitT(t, repoDir, "push", "origin", "main")
+			runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+			commitFile(t, repoDir, "test.txt", "from rc")
+			runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+			head := runGitT(t, repoDir, "rev-parse", "HEAD")
+
+			result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: strategy})
+			assert.NoError(t, result.Err)
+			assert.False(t, result.Updated)
+			assert.Equal(t, []string{"test.txt"}, result.Conflicts)
+
+			dirty, err := isDirty(repoDir)
+			assert.NoError(t, err)
+			assert.False(t, dirty)
+			assert.True(t, checkBranch(repoDir, "protobuf-4.x-rc"))
+			assert.Equal(t, head, runGitT(t, repoDir, "rev-parse", "HEAD"))
+		})
+	}
+
+	t.Run("fast-forwards a stale local branch first", func(t *testing.T) {
+		repoDir := setupClone(t)
+		runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+		commitFile(t, repoDir, "rc.txt", "from rc")
+		runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+		runGitT(t, repoDir, "reset", "--hard", "HEAD~1")
+		runGitT(t, repoDir, "checkout", "main")
+		commitFile(t, repoDir, "main.txt", "from main")
+		runGitT(t, repoDir, "push", "origin", "main")
+
+		result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge"})
+		assert.NoError(t, result.Err)
+		assert.True(t, result.Updated)
+		assert.Equal(t, "from rc", runGitT(t, repoDir, "show", "origin/protobuf-4.x-rc:rc.txt"))
+		assert.Equal(t, "from main", runGitT(t, repoDir, "show", "origin/protobuf-4.x-rc:main.txt"))
+	})
+
+	t.Run("refuses a diverged local branch", func(t *testing.T) {
+		repoDir := setupClone(t)
+		runGitT(t, repoDir, "checkout", "-b", "protobuf-4.x-rc", "origin/protobuf-4.x-rc")
+		commitFile(t, repoDir, "rc.txt", "pushed")
+		runGitT(t, repoDir, "push", "origin", "protobuf-4.x-rc")
+		runGitT(t, repoDir, "reset", "--hard", "HEAD~1")
+		commitFile(t, repoDir, "local.txt", "local only")
+		runGitT(t, repoDir, "checkout", "main")
+		commitFile(t, repoDir, "main.txt", "from main")
+		runGitT(t, repoDir, "push", "origin", "main")
+		remote := runGitT(t, repoDir, "rev-parse", "origin/protobuf-4.x-rc")
+
+		result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge"})
+		assert.ErrorContains(t, result.Err, "diverged")
+		assert.False(t, result.Updated)
+		assert.Equal(t, remote, runGitT(t, repoDir, "rev-parse", "origin/protobuf-4.x-rc"))
+	})
+
+	t.Run("squash twice reports up to date", func(t *testing.T) {
+		repoDir := setupClone(t)
+		commitFile(t, repoDir, "main.txt", "from main")
+		runGitT(t, repoDir, "push", "origin", "main")
+
+		result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "squash"})
+		assert.NoError(t, result.Err)
+		assert.True(t, result.Updated)
+		head := runGitT(t, repoDir, "rev-parse", "origin/protobuf-4.x-rc")
+
+		result = updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "squash"})
+		assert.NoError(t, result.Err)
+		assert.False(t, result.Updated)
+		assert.Equal(t, head, runGitT(t, repoDir, "rev-parse", "origin/protobuf-4.x-rc"))
+		dirty, err := isDirty(repoDir)
+		assert.NoError(t, err)
+		assert.False(t, dirty)
+	})
+
+	t.Run("reports up to date branch", func(t *testing.T) {
+		repoDir := setupClone(t)
+		result := updateBranch(repoDir, "protobuf-4.x-rc", "main", updateBranchOptions{Strategy: "merge"})
+		assert.NoError(t, result.Err)
+		assert.False(t, result.Updated)
+	})
+}
@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -16,56 +14,70 @@ var updateReleasePleaseCmd = &cobra.Command{
 	Short: "Update release-please-config.json to set prerelease",
 	Run: func(cmd *cobra.Command, args []string) {
 		prerelease, _ := cmd.Flags().GetBool("prerelease")
-		updateReleasePlease(prerelease)
+		check, _ := cmd.Flags().GetBool("check")
+		updateReleasePlease(prerelease, check)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(updateReleasePleaseCmd)
 	updateReleasePleaseCmd.Flags().Bool("prerelease", true, "Set to true for prerelease, false otherwise")
+	updateReleasePleaseCmd.Flags().Bool("check", false, "Report files that would change and print a diff without writing them")
 }
 
-func updateReleasePlease(prerelease bool) {
-	repos, err := readLines("github_repositories.txt")
+func updateReleasePlease(prerelease, check bool) {
+	repos, err := readRepos("github_repositories.txt")
 	if err != nil {
 		fmt.Println("Error reading repositories file:", err)
 		return
 	}
 
+	var changed []string
 	for _, repo := range repos {
-		repoDir := filepath.Base(repo)
-		configPath := filepath.Join(repoDir, "release-please-config.json")
-
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		repoDir := repo.Dir()
+		files := locateReleasePleaseFiles(repoDir)
+		if files.ManifestConfig == "" {
 			fmt.Printf("Skipping '%s': release-please-config.json not found\n", repoDir)
 			continue
 		}
+		configPath := files.path(files.ManifestConfig)
+
+		if updateConfig(configPath, prerelease, check) {
+			changed = append(changed, repoDir)
+		}
+	}
 
-		updateConfig(configPath, prerelease)
+	if check {
+		reportCheck(changed)
 	}
 }
 
-func updateConfig(path string, prerelease bool) {
+// updateConfig adds the prerelease option to a release-please-config.json
+// file. In check mode the file is not written. It reports whether the file
+// changed or would change.
+func updateConfig(path string, prerelease, check bool) bool {
 	file, err := ioutil.ReadFile(path)
 	if err != nil {
 		fmt.Printf("Error reading %s: %v\n", path, err)
-		return
+		return false
 	}
 
 	var data map[string]interface{}
 	if err := json.Unmarshal(file, &data); err != nil {
 		fmt.Printf("Error unmarshalling %s: %v\n", path, err)
-		return
+		return false
 	}
 
 	if _, exists := data["prerelease"]; !exists {
-		fmt.Printf("Updating '%s' to set 'prerelease: %v'\n", path, prerelease)
+		if !check {
+			fmt.Printf("Updating '%s' to set 'prerelease: %v'\n", path, prerelease)
+		}
 
 		content := string(file)
 		lastBraceIndex := strings.LastIndex(content, "}")
 		if lastBraceIndex == -1 {
 			fmt.Printf("Could not find closing brace in %s\n", path)
-			return
+			return false
 		}
 
 		// Heuristic to check if a comma is needed.
@@ -81,10 +93,12 @@ func updateConfig(path string, prerelease bool) {
 
 		newContent := content[:lastBraceIndex] + insertion + content[lastBraceIndex:]
 
-		if err := ioutil.WriteFile(path, []byte(newContent), 0644); err != nil {
+		changed, err := writeOrCheck(path, []byte(newContent), check)
+		if err != nil {
 			fmt.Printf("Error writing to %s: %v\n", path, err)
 		}
-	} else {
-		fmt.Printf("Skipping '%s': 'prerelease' key already exists\n", path)
+		return changed
 	}
+	fmt.Printf("Skipping '%s': 'prerelease' key already exists\n", path)
+	return false
 }
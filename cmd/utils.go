@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"os/user"
 	"strings"
 )
@@ -47,3 +48,15 @@ func readToken(path string) (string, error) {
 func GetTokenFromFile(path string) (string, error) {
 	return readToken(path)
 }
+
+// runCommand runs name with args in dir and returns its trimmed output. The
+// output is included in the error if the command fails.
+func runCommand(dir, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w\n%s", name, strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
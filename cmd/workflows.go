@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var workflowsCmd = &cobra.Command{
+	Use:   "workflows",
+	Short: "Edit the GitHub Actions workflows of each repository",
+}
+
+func init() {
+	rootCmd.AddCommand(workflowsCmd)
+}
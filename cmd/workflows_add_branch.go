@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var workflowsAddBranchCmd = &cobra.Command{
+	Use:   "add-branch",
+	Short: "Add the release branch to the branch filters of every workflow",
+	Long: `Add the release branch to the branch filters of every workflow.
+
+Every .github/workflows/*.yaml and *.yml file of each repository is parsed,
+and the branch is appended to the on.push.branches and
+on.pull_request.branches filters that do not already match it. Events
+without a branches filter already run for every branch and are left alone.
+Only the filter is edited; the rest of the file, including comments and
+blank lines, is kept as is.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		branch, _ := cmd.Flags().GetString("branch")
+		events, _ := cmd.Flags().GetStringSlice("events")
+		names, _ := cmd.Flags().GetStringSlice("repos")
+		check, _ := cmd.Flags().GetBool("check")
+
+		repos, err := readRepos("github_repositories.txt")
+		if err != nil {
+			fmt.Println("Error reading repositories file:", err)
+			return
+		}
+		repos, err = selectRepos(repos, names)
+		if err != nil {
+			fmt.Println("Error selecting repositories:", err)
+			return
+		}
+
+		var changed []string
+		for _, repo := range repos {
+			fmt.Printf("--- Updating workflows in %s ---\n", repo.Dir())
+			if addWorkflowsBranch(repo.Dir(), branch, events, check) {
+				changed = append(changed, repo.Dir())
+			}
+		}
+		if check {
+			reportCheck(changed)
+		}
+	},
+}
+
+func init() {
+	workflowsCmd.AddCommand(workflowsAddBranchCmd)
+	workflowsAddBranchCmd.Flags().String("branch", "protobuf-4.x-rc", "The branch to add")
+	workflowsAddBranchCmd.Flags().StringSlice("events", []string{"push", "pull_request"}, "The events whose branch filters are updated")
+	workflowsAddBranchCmd.Flags().StringSlice("repos", nil, "Only update these repositories (name or owner/name)")
+	workflowsAddBranchCmd.Flags().Bool("check", false, "Report files that would change and print a diff without writing them")
+}
+
+// addWorkflowsBranch adds branch to the workflows of repoDir. In check mode
+// the files are not written. It reports whether any file changed or would
+// change.
+func addWorkflowsBranch(repoDir, branch string, events []string, check bool) bool {
+	files, err := filepath.Glob(filepath.Join(repoDir, ".github", "workflows", "*.y*ml"))
+	if err != nil || len(files) == 0 {
+		fmt.Printf("No workflows found in %s, skipping.\n", repoDir)
+		return false
+	}
+
+	changed := false
+	for _, file := range files {
+		if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", file, err)
+			continue
+		}
+		content, filters, err := addWorkflowBranch(string(data), branch, events)
+		if err != nil {
+			fmt.Printf("Error updating %s: %v\n", file, err)
+			continue
+		}
+		if len(filters) == 0 {
+			continue
+		}
+		fmt.Printf("Updating '%s'\n", file)
+		for _, f := range filters {
+			fmt.Printf("  - added %s to %s\n", branch, f)
+		}
+		fileChanged, err := writeOrCheck(file, []byte(content), check)
+		if err != nil {
+			fmt.Printf("Error writing to %s: %v\n", file, err)
+		}
+		changed = changed || fileChanged
+	}
+	return changed
+}
+
+// addWorkflowBranch adds branch to the branches filter of each event in a
+// workflow, unless a pattern in the filter already matches it. The workflow
+// is parsed to find the filters, which are then edited in the text so the
+// rest of the file is unchanged. The result is parsed again to make sure
+// every edited filter now includes branch. It returns the new content and
+// the filters that were changed, such as on.push.branches.
+func addWorkflowBranch(content, branch string, events []string) (string, []string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return "", nil, err
+	}
+
+	var edits []textEdit
+	var edited, filters []string
+	for _, event := range events {
+		branches := workflowBranchFilter(&root, event)
+		if branches == nil || branchFilterMatches(branches, branch) {
+			continue
+		}
+		edit, ok := branchFilterEdit(content, branches, branch)
+		if !ok {
+			return "", nil, fmt.Errorf("cannot edit on.%s.branches at line %d", event, branches.Line)
+		}
+		edits = append(edits, edit)
+		edited = append(edited, event)
+		filters = append(filters, "on."+event+".branches")
+	}
+	if len(edits) == 0 {
+		return content, nil, nil
+	}
+
+	updated, _ := applyEdits(content, edits)
+	var check yaml.Node
+	if err := yaml.Unmarshal([]byte(updated), &check); err != nil {
+		return "", nil, fmt.Errorf("edited workflow does not parse: %w", err)
+	}
+	for _, event := range edited {
+		if branches := workflowBranchFilter(&check, event); branches == nil || !branchFilterMatches(branches, branch) {
+			return "", nil, fmt.Errorf("editing on.%s.branches did not add %s", event, branch)
+		}
+	}
+	return updated, filters, nil
+}
+
+// workflowBranchFilter returns the branches filter of an event in a parsed
+// workflow, or nil if the event runs for every branch or not at all.
+func workflowBranchFilter(root *yaml.Node, event string) *yaml.Node {
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	on := mappingValue(root.Content[0], "on")
+	if on == nil || on.Kind != yaml.MappingNode {
+		// on: push or on: [push, pull_request] runs for every branch.
+		return nil
+	}
+	trigger := mappingValue(on, event)
+	if trigger == nil || trigger.Kind != yaml.MappingNode {
+		return nil
+	}
+	return mappingValue(trigger, "branches")
+}
+
+// branchFilterMatches reports whether a branches filter, a single pattern
+// or a sequence of patterns, includes branch.
+func branchFilterMatches(filter *yaml.Node, branch string) bool {
+	patterns := []*yaml.Node{filter}
+	if filter.Kind == yaml.SequenceNode {
+		patterns = filter.Content
+	}
+	for _, p := range patterns {
+		if p.Value == branch {
+			return true
+		}
+		if ok, _ := path.Match(p.Value, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// branchFilterEdit returns the text edit adding branch to a branches
+// filter, keeping the filter's style: a new "- branch" line for block
+// sequences, ", branch" for flow sequences, and a flow sequence replacing a
+// single pattern. The new entry is quoted like the last existing one. Block
+// scalars and scalars spanning several lines are not supported.
+func branchFilterEdit(content string, filter *yaml.Node, branch string) (textEdit, bool) {
+	lines := strings.SplitAfter(content, "\n")
+	// offset converts a line and column from the parser, which counts
+	// columns in runes, to a byte offset in content.
+	offset := func(line, column int) int {
+		n := 0
+		for _, l := range lines[:line-1] {
+			n += len(l)
+		}
+		if line <= len(lines) {
+			n += len(string([]rune(lines[line-1])[:column-1]))
+		}
+		return n
+	}
+	isBlockScalar := func(node *yaml.Node) bool {
+		return node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0
+	}
+
+	switch {
+	case filter.Kind == yaml.ScalarNode:
+		if isBlockScalar(filter) {
+			return textEdit{}, false
+		}
+		start := offset(filter.Line, filter.Column)
+		end, ok := scalarEnd(content, start, false)
+		if !ok {
+			return textEdit{}, false
+		}
+		return textEdit{Start: start, End: end, Text: "[" + content[start:end] + ", " + quoteLike(filter, branch) + "]"}, true
+
+	case filter.Kind == yaml.SequenceNode && len(filter.Content) == 0:
+		if filter.Style&yaml.FlowStyle == 0 {
+			return textEdit{}, false
+		}
+		// Insert after the opening bracket of [].
+		start := offset(filter.Line, filter.Column) + 1
+		return textEdit{Start: start, End: start, Text: branch}, true
+
+	case filter.Kind == yaml.SequenceNode && filter.Style&yaml.FlowStyle != 0:
+		last := filter.Content[len(filter.Content)-1]
+		if last.Kind != yaml.ScalarNode {
+			return textEdit{}, false
+		}
+		end, ok := scalarEnd(content, offset(last.Line, last.Column), true)
+		if !ok {
+			return textEdit{}, false
+		}
+		return textEdit{Start: end, End: end, Text: ", " + quoteLike(last, branch)}, true
+
+	case filter.Kind == yaml.SequenceNode:
+		last := filter.Content[len(filter.Content)-1]
+		if last.Kind != yaml.ScalarNode || isBlockScalar(last) || last.Line > len(lines) {
+			return textEdit{}, false
+		}
+		if _, ok := scalarEnd(content, offset(last.Line, last.Column), false); !ok {
+			return textEdit{}, false
+		}
+		// Repeat the indentation and "- " of the last entry on a new line.
+		line := lines[last.Line-1]
+		prefix := line[:offset(last.Line, last.Column)-offset(last.Line, 1)]
+		text := prefix + quoteLike(last, branch) + "\n"
+		if !strings.HasSuffix(line, "\n") {
+			text = "\n" + strings.TrimSuffix(text, "\n")
+		}
+		start := offset(last.Line+1, 1)
+		return textEdit{Start: start, End: start, Text: text}, true
+	}
+	return textEdit{}, false
+}
+
+// scalarEnd returns the byte offset just past the raw text of the scalar
+// starting at start in content, or false if the scalar does not end on the
+// same line. Plain scalars end before a comment, and in a flow sequence also
+// before the next separator.
+func scalarEnd(content string, start int, flow bool) (int, bool) {
+	text := content[start:]
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	switch {
+	case strings.HasPrefix(text, "'"):
+		for i := 1; i < len(text); i++ {
+			if text[i] != '\'' {
+				continue
+			}
+			// '' is an escaped quote.
+			if i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return start + i + 1, true
+		}
+		return 0, false
+	case strings.HasPrefix(text, `"`):
+		for i := 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '"':
+				return start + i + 1, true
+			}
+		}
+		return 0, false
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = text[:i]
+	}
+	if flow {
+		if i := strings.IndexAny(text, ",]}"); i >= 0 {
+			text = text[:i]
+		}
+	}
+	return start + len(strings.TrimRight(text, " \t\r")), true
+}
+
+// quoteLike renders value as a scalar in the quoting style of node.
+func quoteLike(node *yaml.Node, value string) string {
+	switch {
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+	return value
+}
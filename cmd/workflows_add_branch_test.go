@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddWorkflowBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		want     string
+		branches []string
+	}{
+		{
+			name: "block sequences",
+			content: `on:
+  push:
+    branches:
+      - main
+    # Only run for Java changes.
+    paths: ['**.java']
+
+  pull_request:
+    branches:
+    - 'main'
+jobs: {}
+`,
+			want: `on:
+  push:
+    branches:
+      - main
+      - protobuf-4.x-rc
+    # Only run for Java changes.
+    paths: ['**.java']
+
+  pull_request:
+    branches:
+    - 'main'
+    - 'protobuf-4.x-rc'
+jobs: {}
+`,
+			branches: []string{"on.push.branches", "on.pull_request.branches"},
+		},
+		{
+			name: "flow sequence and scalar",
+			content: `on:
+  push:
+    branches: [ main, "java7" ]
+  pull_request:
+    branches: main
+`,
+			want: `on:
+  push:
+    branches: [ main, "java7", "protobuf-4.x-rc" ]
+  pull_request:
+    branches: [main, protobuf-4.x-rc]
+`,
+			branches: []string{"on.push.branches", "on.pull_request.branches"},
+		},
+		{
+			name:    "all branches",
+			content: "on: [push, pull_request]\n",
+			want:    "on: [push, pull_request]\n",
+		},
+		{
+			name: "no branch filter or matching pattern",
+			content: `on:
+  push:
+    tags: ['v*']
+  pull_request:
+    branches: ['protobuf-*']
+  workflow_dispatch:
+`,
+			want: `on:
+  push:
+    tags: ['v*']
+  pull_request:
+    branches: ['protobuf-*']
+  workflow_dispatch:
+`,
+		},
+		{
+			name: "multibyte characters and escapes",
+			content: `on:
+  push:
+    branches: [ 'déploiement', "rel\x61se" ] # même
+  pull_request:
+    branches: "rel\x61se" # même
+`,
+			want: `on:
+  push:
+    branches: [ 'déploiement', "rel\x61se", "protobuf-4.x-rc" ] # même
+  pull_request:
+    branches: ["rel\x61se", "protobuf-4.x-rc"] # même
+`,
+			branches: []string{"on.push.branches", "on.pull_request.branches"},
+		},
+		{
+			name:     "no trailing newline",
+			content:  "on:\n  pull_request:\n    branches:\n      - main",
+			want:     "on:\n  pull_request:\n    branches:\n      - main\n      - protobuf-4.x-rc",
+			branches: []string{"on.pull_request.branches"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, branches, err := addWorkflowBranch(tt.content, "protobuf-4.x-rc", []string{"push", "pull_request"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.branches, branches)
+
+			again, branches, err := addWorkflowBranch(got, "protobuf-4.x-rc", []string{"push", "pull_request"})
+			assert.NoError(t, err)
+			assert.Equal(t, got, again)
+			assert.Empty(t, branches)
+		})
+	}
+}
+
+func TestAddWorkflowBranchBlockScalar(t *testing.T) {
+	content := "on:\n  push:\n    branches: >-\n      main\n"
+	_, _, err := addWorkflowBranch(content, "protobuf-4.x-rc", []string{"push"})
+	assert.Error(t, err)
+}
+
+func TestAddWorkflowsBranch(t *testing.T) {
+	repoDir := t.TempDir()
+	workflows := filepath.Join(repoDir, ".github", "workflows")
+	assert.NoError(t, os.MkdirAll(workflows, 0755))
+	ci := "on:\n  pull_request:\n    branches:\n      - main\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(workflows, "ci.yaml"), []byte(ci), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(workflows, "lint.yml"), []byte("on: pull_request\n"), 0644))
+
+	assert.True(t, addWorkflowsBranch(repoDir, "protobuf-4.x-rc", []string{"push", "pull_request"}, true))
+	data, err := os.ReadFile(filepath.Join(workflows, "ci.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, ci, string(data))
+
+	assert.True(t, addWorkflowsBranch(repoDir, "protobuf-4.x-rc", []string{"push", "pull_request"}, false))
+	data, err = os.ReadFile(filepath.Join(workflows, "ci.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, ci+"      - protobuf-4.x-rc\n", string(data))
+	assert.False(t, addWorkflowsBranch(repoDir, "protobuf-4.x-rc", []string{"push", "pull_request"}, false))
+}
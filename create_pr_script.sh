@@ -7,6 +7,9 @@ if [ -z "$REPO_DIR" ]; then
   exit 1
 fi
 
+# The repo-manager binary locates the release-please files to commit.
+REPO_MANAGER=${REPO_MANAGER:-"$(pwd)/repo-manager"}
+
 cd "$REPO_DIR" || exit
 
 BRANCH_NAME="chore/cleanup-release-please"
@@ -20,9 +23,15 @@ echo "--- Processing repository: $(basename "$REPO_DIR") ---"
 echo "Creating branch $BRANCH_NAME..."
 git checkout -b "$BRANCH_NAME"
 
-# 2. Add the modified release-please.yml file
-echo "Adding .github/release-please.yml to staging..."
-git add .github/release-please.yml
+# 2. Add the modified release-please files (.yml or .yaml, manifest and config)
+RELEASE_PLEASE_FILES=$("$REPO_MANAGER" release-please-files .)
+if [ -z "$RELEASE_PLEASE_FILES" ]; then
+  echo "No release-please files found in $(basename "$REPO_DIR")"
+  exit 1
+fi
+echo "Adding release-please files to staging:"
+echo "$RELEASE_PLEASE_FILES"
+echo "$RELEASE_PLEASE_FILES" | xargs git add --
 
 # 3. Commit the changes
 echo "Committing changes..."